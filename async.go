@@ -0,0 +1,243 @@
+package glog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// what to do with a message when msgChan is full
+type OverflowPolicy int
+
+const (
+	// wait until the writer goroutine has room, no message is lost
+	OverflowBlock OverflowPolicy = iota
+	// drop the message being logged
+	OverflowDropNewest
+	// drop the oldest queued message to make room
+	OverflowDropOldest
+	// drop messages below AsyncConfig.DropLevel, block for the others
+	OverflowDropBelowLevel
+)
+
+const defaultAsyncBufferSize = 100
+
+// async mode config
+type AsyncConfig struct {
+	// cap of msgChan, default 100
+	BufferSize int
+
+	Overflow OverflowPolicy
+
+	// used by OverflowDropBelowLevel
+	DropLevel LOGLEVEL
+}
+
+// writer goroutine of an async logger
+type asyncWriter struct {
+	config    AsyncConfig
+	lock      sync.RWMutex // senders hold the read lock, stop holds the write lock
+	closed    bool
+	msgChan   chan *loggerMsg
+	flushChan chan chan struct{}
+	stopChan  chan struct{}
+	doneChan  chan struct{} // closed when the writer goroutine exits
+	dropped   *uint64       // dropped counter of the owner logger
+}
+
+func newAsyncWriter(config AsyncConfig, dropped *uint64) *asyncWriter {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultAsyncBufferSize
+	}
+	return &asyncWriter{
+		config:    config,
+		msgChan:   make(chan *loggerMsg, config.BufferSize),
+		flushChan: make(chan chan struct{}),
+		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		dropped:   dropped,
+	}
+}
+
+// queue a message by the overflow policy
+// return : false if the writer is stopped, the caller should write synchronously
+func (aw *asyncWriter) push(loggerMsg *loggerMsg) bool {
+	aw.lock.RLock()
+	defer aw.lock.RUnlock()
+
+	if aw.closed {
+		return false
+	}
+
+	switch aw.config.Overflow {
+	case OverflowDropNewest:
+		select {
+		case aw.msgChan <- loggerMsg:
+		default:
+			aw.drop(loggerMsg)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case aw.msgChan <- loggerMsg:
+				return true
+			default:
+			}
+			select {
+			case oldest := <-aw.msgChan:
+				aw.drop(oldest)
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if levelEnabled(aw.config.DropLevel, loggerMsg.Ilevel) {
+			aw.msgChan <- loggerMsg
+			break
+		}
+		select {
+		case aw.msgChan <- loggerMsg:
+		default:
+			aw.drop(loggerMsg)
+		}
+	default:
+		aw.msgChan <- loggerMsg
+	}
+	return true
+}
+
+// count a dropped message and return it to the pool
+func (aw *asyncWriter) drop(loggerMsg *loggerMsg) {
+	atomic.AddUint64(aw.dropped, 1)
+	putLoggerMsg(loggerMsg)
+}
+
+// read msgChan until stopped
+func (aw *asyncWriter) run(logger *Logger) {
+	defer close(aw.doneChan)
+	for {
+		select {
+		case loggerMsg := <-aw.msgChan:
+			aw.write(logger, loggerMsg)
+		case done := <-aw.flushChan:
+			aw.drain(logger)
+			logger.flush()
+			close(done)
+		case <-aw.stopChan:
+			aw.drain(logger)
+			logger.flush()
+			return
+		}
+	}
+}
+
+// write a message, a panic in an adapter must not kill the writer goroutine
+func (aw *asyncWriter) write(logger *Logger, loggerMsg *loggerMsg) {
+	defer func() {
+		if e := recover(); e != nil {
+			fmt.Fprintf(os.Stderr, "logger: async write panic: %v\n", e)
+		}
+		putLoggerMsg(loggerMsg)
+	}()
+	logger.writeToOutputs(loggerMsg)
+}
+
+// write all queued messages
+func (aw *asyncWriter) drain(logger *Logger) {
+	for {
+		select {
+		case loggerMsg := <-aw.msgChan:
+			aw.write(logger, loggerMsg)
+		default:
+			return
+		}
+	}
+}
+
+// wait until queued messages are written and adapters flushed
+// return : false if the writer is already stopped
+func (aw *asyncWriter) flush() bool {
+	done := make(chan struct{})
+	select {
+	case aw.flushChan <- done:
+		<-done
+		return true
+	case <-aw.doneChan:
+		return false
+	}
+}
+
+// stop accepting messages and wait the writer goroutine drain msgChan
+func (aw *asyncWriter) stop(ctx context.Context) error {
+	aw.lock.Lock()
+	if !aw.closed {
+		aw.closed = true
+		close(aw.stopChan)
+	}
+	aw.lock.Unlock()
+
+	select {
+	case <-aw.doneChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// get the async writer, nil when the logger is synchronous
+func (logger *Logger) asyncWriter() *asyncWriter {
+	aw, _ := logger.base().async.Load().(*asyncWriter)
+	return aw
+}
+
+// set logger synchronous false
+// params : buf , if not set, default cap(msgChan) = 100, if set, cap(msgChan) = buf[0]
+func (logger *Logger) SetAsync(buf ...int) {
+	config := AsyncConfig{}
+	if len(buf) > 0 {
+		config.BufferSize = buf[0]
+	}
+	logger.SetAsyncConfig(config)
+}
+
+// set logger synchronous false with overflow policy, child loggers share the async writer of their root
+// a running async writer is drained and stopped before the new one starts, see output for messages logged meanwhile
+func (logger *Logger) SetAsyncConfig(config AsyncConfig) {
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	if old := root.asyncWriter(); old != nil {
+		old.stop(context.Background())
+	}
+	aw := newAsyncWriter(config, &root.dropped)
+	root.async.Store(aw)
+	go aw.run(root)
+}
+
+// drain and stop the async writer, then flush all adapters
+// the logger keeps working synchronously after Close
+// return : ctx.Err() if ctx is done before msgChan is drained, the writer goroutine keeps draining
+// and messages logged meanwhile are written after the queued ones, see output
+func (logger *Logger) Close(ctx context.Context) error {
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	aw := root.asyncWriter()
+	if aw == nil {
+		root.flush()
+		return nil
+	}
+	if err := aw.stop(ctx); err != nil {
+		return err
+	}
+	root.async.Store((*asyncWriter)(nil))
+	return nil
+}
+
+// number of messages dropped by the overflow policy
+func (logger *Logger) Dropped() uint64 {
+	return atomic.LoadUint64(&logger.base().dropped)
+}
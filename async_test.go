@@ -0,0 +1,225 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writer blocks the first Write until release is closed
+type blockingWriter struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+	lock    sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.entered)
+		<-w.release
+	})
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func newBlockingLogger(w *blockingWriter) *Logger {
+	console := NewConsoleAdapter(DEBUG, false, false)
+	console.(*ConsoleAdapter).writer = w
+	logger, _ := NewLogger(DashMillisecondFormat, false, console)
+	return logger
+}
+
+func TestAsyncClose(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+	logger.SetAsync(10)
+	for i := 0; i < 100; i++ {
+		logger.Infof("msg %d", i)
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 100 {
+		t.Errorf("wanted lines : 100, actual: %d", lines)
+	}
+
+	// logger keeps working synchronously after Close
+	logger.Info("after close")
+	if !strings.Contains(buf.String(), "after close") {
+		t.Errorf("message after Close not written")
+	}
+}
+
+func TestAsyncSetTwice(t *testing.T) {
+	logger := newBufferLogger(&bytes.Buffer{}, false)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		logger.SetAsync()
+	}
+	logger.Close(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked, before: %d, after: %d", before, after)
+	}
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := newBlockingLogger(w)
+	logger.SetAsyncConfig(AsyncConfig{BufferSize: 1, Overflow: OverflowDropNewest})
+
+	logger.Info("msg 1")
+	<-w.entered
+	logger.Info("msg 2")
+	logger.Info("msg 3")
+	close(w.release)
+	logger.Close(context.Background())
+
+	out := w.String()
+	if !strings.Contains(out, "msg 1") || !strings.Contains(out, "msg 2") || strings.Contains(out, "msg 3") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if logger.Dropped() != 1 {
+		t.Errorf("wanted dropped : 1, actual: %d", logger.Dropped())
+	}
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := newBlockingLogger(w)
+	logger.SetAsyncConfig(AsyncConfig{BufferSize: 2, Overflow: OverflowDropOldest})
+
+	logger.Info("msg 1")
+	<-w.entered
+	logger.Info("msg 2")
+	logger.Info("msg 3")
+	// the queue is full, msg 2 makes room for msg 4
+	logger.Info("msg 4")
+	close(w.release)
+	logger.Close(context.Background())
+
+	out := w.String()
+	if strings.Contains(out, "msg 2") || !strings.Contains(out, "msg 3") || !strings.Contains(out, "msg 4") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if logger.Dropped() != 1 {
+		t.Errorf("wanted dropped : 1, actual: %d", logger.Dropped())
+	}
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := newBlockingLogger(w)
+	logger.SetAsyncConfig(AsyncConfig{BufferSize: 1, Overflow: OverflowDropBelowLevel, DropLevel: WARN})
+
+	logger.Info("msg 1")
+	<-w.entered
+	logger.Info("msg 2")
+	logger.Info("msg 3")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(w.release)
+	}()
+	logger.Error("msg 4")
+	logger.Close(context.Background())
+
+	out := w.String()
+	if !strings.Contains(out, "msg 2") || strings.Contains(out, "msg 3") || !strings.Contains(out, "msg 4") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if logger.Dropped() != 1 {
+		t.Errorf("wanted dropped : 1, actual: %d", logger.Dropped())
+	}
+}
+
+func TestAsyncSetWhileWriting(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := newBlockingLogger(w)
+	logger.SetAsync(10)
+
+	logger.Info("msg 1")
+	<-w.entered
+	logger.Info("msg 2")
+	logger.Info("msg 3")
+
+	// msg 4 is refused by the stopping writer, it must not be written before msg 2 and msg 3
+	var wait sync.WaitGroup
+	wait.Add(2)
+	go func() {
+		defer wait.Done()
+		logger.SetAsync(10)
+	}()
+	go func() {
+		defer wait.Done()
+		time.Sleep(10 * time.Millisecond)
+		logger.Info("msg 4")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(w.release)
+	wait.Wait()
+	logger.Info("msg 5")
+	logger.Close(context.Background())
+
+	out := w.String()
+	last := -1
+	for i := 1; i <= 5; i++ {
+		index := strings.Index(out, fmt.Sprintf("msg %d", i))
+		if index <= last {
+			t.Fatalf("msg %d missing or out of order: %s", i, out)
+		}
+		last = index
+	}
+}
+
+func TestAsyncCloseTimeout(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := newBlockingLogger(w)
+	logger.SetAsync(10)
+
+	logger.Info("msg 1")
+	<-w.entered
+	logger.Info("msg 2")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := logger.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wanted context.DeadlineExceeded, actual: %v", err)
+	}
+
+	// msg 3 waits until the writer left by Close has written msg 2
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("msg 3")
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(w.release)
+	<-done
+	logger.Info("msg 4")
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := w.String()
+	last := -1
+	for i := 1; i <= 4; i++ {
+		index := strings.Index(out, fmt.Sprintf("msg %d", i))
+		if index <= last {
+			t.Fatalf("msg %d missing or out of order: %s", i, out)
+		}
+		last = index
+	}
+}
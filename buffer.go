@@ -0,0 +1,96 @@
+package glog
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultBufferSize = 1024
+
+// buffers larger than this are not returned to the pool
+const maxPooledBufferSize = 64 * 1024
+
+// Buffer is a byte buffer used by encoders, get one by GetBuffer and release it by Free
+type Buffer struct {
+	bs []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{bs: make([]byte, 0, defaultBufferSize)}
+	},
+}
+
+// GetBuffer get an empty Buffer from the pool
+func GetBuffer() *Buffer {
+	buf := bufferPool.Get().(*Buffer)
+	buf.bs = buf.bs[:0]
+	return buf
+}
+
+// Free return the Buffer to the pool, the Buffer must not be used after Free
+func (buf *Buffer) Free() {
+	if cap(buf.bs) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+func (buf *Buffer) AppendByte(b byte) {
+	buf.bs = append(buf.bs, b)
+}
+
+func (buf *Buffer) AppendBytes(b []byte) {
+	buf.bs = append(buf.bs, b...)
+}
+
+func (buf *Buffer) AppendString(s string) {
+	buf.bs = append(buf.bs, s...)
+}
+
+func (buf *Buffer) AppendInt(i int64) {
+	buf.bs = strconv.AppendInt(buf.bs, i, 10)
+}
+
+func (buf *Buffer) AppendUint(i uint64) {
+	buf.bs = strconv.AppendUint(buf.bs, i, 10)
+}
+
+func (buf *Buffer) AppendFloat(f float64, bitSize int) {
+	buf.bs = strconv.AppendFloat(buf.bs, f, 'g', -1, bitSize)
+}
+
+func (buf *Buffer) AppendBool(b bool) {
+	buf.bs = strconv.AppendBool(buf.bs, b)
+}
+
+func (buf *Buffer) AppendTime(t time.Time, layout string) {
+	buf.bs = t.AppendFormat(buf.bs, layout)
+}
+
+func (buf *Buffer) AppendQuote(s string) {
+	buf.bs = strconv.AppendQuote(buf.bs, s)
+}
+
+// Write implements io.Writer
+func (buf *Buffer) Write(p []byte) (int, error) {
+	buf.bs = append(buf.bs, p...)
+	return len(p), nil
+}
+
+func (buf *Buffer) Bytes() []byte {
+	return buf.bs
+}
+
+func (buf *Buffer) String() string {
+	return string(buf.bs)
+}
+
+func (buf *Buffer) Len() int {
+	return len(buf.bs)
+}
+
+func (buf *Buffer) Reset() {
+	buf.bs = buf.bs[:0]
+}
@@ -0,0 +1,74 @@
+package glog
+
+import (
+	"path"
+	"runtime"
+	"strings"
+)
+
+// what caller information a Logger records, only used when callerFlag is true
+type CallerConfig struct {
+	// record the full file path instead of the file name
+	FullPath bool
+
+	// record the function name, ex "(*Server).Handle"
+	Function bool
+
+	// record the package path, ex "github.com/chgxtony/glog"
+	Package bool
+}
+
+// set what caller information is recorded, it also enables caller capture
+// call it before logging starts, like the callerFlag of NewLogger
+func (logger *Logger) SetCallerConfig(config CallerConfig) {
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	root.callerFlag = true
+	root.caller = config
+}
+
+// create a child logger which skips n more caller frames,
+// use it when the logger is wrapped by a helper function so the helper's caller is recorded
+func (logger *Logger) AddCallerSkip(n int) *Logger {
+	return &Logger{
+		root:       logger.base(),
+		name:       logger.name,
+		fields:     logger.fields,
+		callerSkip: logger.callerSkip + n,
+	}
+}
+
+// fill File, Line, Func and Package of loggerMsg by the caller config
+func (logger *Logger) setCaller(loggerMsg *loggerMsg, frame runtime.Frame) {
+	config := logger.caller
+	if config.FullPath {
+		loggerMsg.File = frame.File
+	} else {
+		_, loggerMsg.File = path.Split(frame.File)
+	}
+	loggerMsg.Line = frame.Line
+
+	if !config.Function && !config.Package {
+		return
+	}
+	pkg, funcName := splitFuncName(frame.Function)
+	if config.Function {
+		loggerMsg.Func = funcName
+	}
+	if config.Package {
+		loggerMsg.Package = pkg
+	}
+}
+
+// split "github.com/chgxtony/glog.(*Logger).Info" into "github.com/chgxtony/glog" and "(*Logger).Info"
+func splitFuncName(name string) (string, string) {
+	lastSlash := strings.LastIndexByte(name, '/')
+	dot := strings.IndexByte(name[lastSlash+1:], '.')
+	if dot < 0 {
+		return "", name
+	}
+	dot += lastSlash + 1
+	return name[:dot], name[dot+1:]
+}
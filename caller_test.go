@@ -0,0 +1,104 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// line of the caller
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// a logging helper, AddCallerSkip(1) makes the helper's caller recorded
+func logHelper(logger *Logger, msg string) {
+	logger.AddCallerSkip(1).Info(msg)
+}
+
+func TestCallerLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	logger.SetExitFunc(func(int) {})
+	ctx := context.Background()
+
+	calls := map[string]func() int{
+		"Info":          func() int { logger.Info("m"); return currentLine() },
+		"Infof":         func() int { logger.Infof("%s", "m"); return currentLine() },
+		"Infow":         func() int { logger.Infow("m", "k", 1); return currentLine() },
+		"Debug":         func() int { logger.Debug("m"); return currentLine() },
+		"Warnf":         func() int { logger.Warnf("%s", "m"); return currentLine() },
+		"Errorw":        func() int { logger.Errorw("m"); return currentLine() },
+		"Fatal":         func() int { logger.Fatal("m"); return currentLine() },
+		"Fatalf":        func() int { logger.Fatalf("%s", "m"); return currentLine() },
+		"InfoCtx":       func() int { logger.InfoCtx(ctx, "m"); return currentLine() },
+		"ErrorCtx":      func() int { logger.ErrorCtx(ctx, "m"); return currentLine() },
+		"With":          func() int { logger.With("k", 1).Info("m"); return currentLine() },
+		"Named":         func() int { logger.Named("db").Warn("m"); return currentLine() },
+		"AddCallerSkip": func() int { logHelper(logger, "m"); return currentLine() },
+		"Panic": func() (line int) {
+			defer func() { recover() }()
+			line = currentLine() + 1
+			logger.Panic("m")
+			return
+		},
+	}
+	for name, call := range calls {
+		buf.Reset()
+		line := call()
+		res := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+			t.Fatalf("%s: %v, %s", name, err, buf.String())
+		}
+		if res["file"] != "caller_test.go" || res["line"] != float64(line) {
+			t.Errorf("%s: wanted caller_test.go:%d, actual: %v:%v", name, line, res["file"], res["line"])
+		}
+	}
+}
+
+func TestCallerConfig(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	logger.SetCallerConfig(CallerConfig{FullPath: true, Function: true, Package: true})
+
+	logger.Info("m")
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if file, _ := res["file"].(string); !strings.HasSuffix(file, "/caller_test.go") {
+		t.Errorf("wanted full path, actual: %v", res["file"])
+	}
+	if res["func"] != "TestCallerConfig" || res["package"] != "github.com/chgxtony/glog" {
+		t.Errorf("wanted TestCallerConfig in github.com/chgxtony/glog, actual: %v in %v", res["func"], res["package"])
+	}
+
+	buf.Reset()
+	logger = newBufferLogger(buf, false)
+	logger.SetCallerConfig(CallerConfig{Function: true})
+	func() { logger.Info("m") }()
+	if want := "[caller_test.go:"; !strings.Contains(buf.String(), want) {
+		t.Errorf("wanted %s, actual: %s", want, buf.String())
+	}
+	if want := " TestCallerConfig.func1] m"; !strings.Contains(buf.String(), want) {
+		t.Errorf("wanted %s, actual: %s", want, buf.String())
+	}
+}
+
+func TestSplitFuncName(t *testing.T) {
+	cases := [][3]string{
+		{"github.com/chgxtony/glog.(*Logger).Info", "github.com/chgxtony/glog", "(*Logger).Info"},
+		{"main.main", "main", "main"},
+		{"noPackage", "", "noPackage"},
+	}
+	for _, c := range cases {
+		pkg, fn := splitFuncName(c[0])
+		if pkg != c[1] || fn != c[2] {
+			t.Errorf("%s: wanted %s %s, actual: %s %s", c[0], c[1], c[2], pkg, fn)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package glog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// compress rotated log files, implement it to plug in another format such as zstd
+type Compressor interface {
+	// file name extension of compressed files, ex ".gz"
+	Extension() string
+	// compress src into dst
+	Compress(dst io.Writer, src io.Reader) error
+}
+
+// a Compressor which can also read its files back, used by OpenLogFile and FileLines
+type Decompressor interface {
+	// decompress src, closing the returned reader does not close src
+	Decompress(src io.Reader) (io.ReadCloser, error)
+}
+
+// extension of files compressed by GzipCompressor
+const gzipExtension = ".gz"
+
+// compressed file names OpenLogFile refuses to read as plain text without a Decompressor
+var compressedExtensions = []string{".zst", ".bz2", ".xz", ".lz4", ".br", ".z"}
+
+// returned by OpenLogFile for compressed files it can not decompress
+var ErrNoDecompressor = errors.New("logger: no decompressor for file")
+
+// gzip Compressor, app_20261017.log is compressed to app_20261017.log.gz
+type GzipCompressor struct {
+	// gzip.BestSpeed to gzip.BestCompression, if 0, gzip.DefaultCompression
+	Level int
+}
+
+func (GzipCompressor) Extension() string {
+	return gzipExtension
+}
+
+func (compressor GzipCompressor) Compress(dst io.Writer, src io.Reader) error {
+	level := compressor.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	w, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (GzipCompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(src)
+}
+
+// compress filename into filename + Extension(), then remove filename
+// the compressed file keeps the modification time, so MaxAge still works
+func compressFile(compressor Compressor, filename string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// write a temporary file first, a half written file must never look like a compressed log
+	dstName := filename + compressor.Extension()
+	tmpName := dstName + ".tmp"
+	dst, err := os.OpenFile(tmpName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = compressor.Compress(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmpName, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmpName, dstName)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(filename)
+}
+
+// open a log file for reading, files ending with .gz are decompressed
+// files ending with the Extension() of compressors are decompressed by them if they implement Decompressor
+// other compressed files such as .zst return ErrNoDecompressor instead of compressed bytes
+func OpenLogFile(filename string, compressors ...Compressor) (io.ReadCloser, error) {
+	var decompressor Decompressor
+	compressed := false
+	for _, compressor := range append(compressors, GzipCompressor{}) {
+		if compressor != nil && strings.HasSuffix(filename, compressor.Extension()) {
+			decompressor, _ = compressor.(Decompressor)
+			compressed = true
+			break
+		}
+	}
+	for _, extension := range compressedExtensions {
+		compressed = compressed || strings.HasSuffix(filename, extension)
+	}
+	if compressed && decompressor == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoDecompressor, filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if decompressor == nil {
+		return file, nil
+	}
+	r, err := decompressor.Decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &decompressedFile{ReadCloser: r, file: file}, nil
+}
+
+// a decompressing reader closing its file
+type decompressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f *decompressedFile) Close() error {
+	err := f.ReadCloser.Close()
+	if fileErr := f.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
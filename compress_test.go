@@ -0,0 +1,116 @@
+package glog
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app_20261017.log")
+	if err := ioutil.WriteFile(filename, []byte("a\nb\nc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(filename, modTime, modTime)
+
+	if err := compressFile(GzipCompressor{}, filename); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirFiles(t, dir); strings.Join(got, ",") != "app_20261017.log.gz" {
+		t.Errorf("wanted app_20261017.log.gz only, actual: %v", got)
+	}
+	info, err := os.Stat(filename + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("wanted modification time %v, actual: %v", modTime, info.ModTime())
+	}
+
+	lines, err := FileLines(filename + ".gz")
+	if err != nil || lines != 3 {
+		t.Errorf("wanted 3 lines, actual: %d, %v", lines, err)
+	}
+	r, err := OpenLogFile(filename + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := ioutil.ReadAll(r)
+	if string(data) != "a\nb\nc\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+// a copyCompressor reading its files back
+type copyDecompressor struct {
+	copyCompressor
+}
+
+func (copyDecompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(src), nil
+}
+
+func TestOpenLogFileCompressors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app_20261017.log.zst")
+	if err := ioutil.WriteFile(filename, []byte("a\nb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// compressed bytes are never counted as lines
+	for _, compressors := range [][]Compressor{nil, {copyCompressor{".zst"}}, {GzipCompressor{}}} {
+		if _, err := FileLines(filename, compressors...); !errors.Is(err, ErrNoDecompressor) {
+			t.Errorf("%v: wanted ErrNoDecompressor, actual: %v", compressors, err)
+		}
+	}
+	lines, err := FileLines(filename, GzipCompressor{}, copyDecompressor{copyCompressor{".zst"}})
+	if err != nil || lines != 2 {
+		t.Errorf("wanted 2 lines, actual: %d, %v", lines, err)
+	}
+}
+
+func TestFileAdapterCompressor(t *testing.T) {
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, logger, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_DAY,
+		MaxLine:     2,
+		MaxBackups:  2,
+		Compressor:  GzipCompressor{},
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 7; i++ {
+		logger.Info("line")
+	}
+	logger.Detach(adapter.ID())
+	if err := adapter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app.log", "app_20261017.2.log.gz", "app_20261017.3.log.gz"}
+	if got := dirFiles(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("wanted : %v, actual: %v", want, got)
+	}
+	if lines, err := FileLines(filepath.Join(dir, want[2])); err != nil || lines != 2 {
+		t.Errorf("wanted 2 lines, actual: %d, %v", lines, err)
+	}
+}
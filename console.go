@@ -2,10 +2,11 @@ package glog
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"sync"
+	"time"
 )
 
 const CONSOLE_ADAPTER_NAME = "console"
@@ -45,42 +46,83 @@ func TextWithColor(color COLOR, msg string) string {
 
 var LevelColorMap = map[LOGLEVEL]COLOR{
 	FATAL: magenta,
+	PANIC: redBg,
 	ERROR: red,
 	WARN:  yellow,
 	INFO:  green,
 	DEBUG: white,
+	TRACE: cyan,
 }
 
 func levelColor(logLevel LOGLEVEL) COLOR {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
 	return LevelColorMap[logLevel]
 }
 
 type ConsoleConfig struct {
-	JsonFlag  bool
-	LogLevel  LOGLEVEL
-	ColorFlag bool //console adapter 独有的配置项
+	JsonFlag     bool
+	LogEncoder   Encoder        // if nil, selected by JsonFlag
+	TimeFormat   string         // time format of the default encoder, if empty, the logger time format is used
+	TimeLocation *time.Location // time zone of the default encoder, ex time.UTC, if nil, local time
+	LogLevel     LOGLEVEL
+	ColorFlag    bool //console adapter 独有的配置项
 	LoggerConfig
+
+	level   adapterLevel
+	encoder adapterEncoder
 }
 
 func (config *ConsoleConfig) Level() LOGLEVEL {
-	return config.LogLevel
+	return config.level.get(config.LogLevel)
 }
 
+// safe while messages are written, Level() returns loglevel from now on
 func (config *ConsoleConfig) SetLevel(loglevel LOGLEVEL) {
-	config.LogLevel = loglevel
+	config.level.set(loglevel)
 }
 
 func (config *ConsoleConfig) IsJson() bool {
 	return config.JsonFlag
 }
 
+// the encoder built by Init, SetEncoder or SetTimeFormat, safe while they run
+func (config *ConsoleConfig) Encoder() Encoder {
+	if encoder := config.encoder.get(); encoder != nil {
+		return encoder
+	}
+	if config.LogEncoder == nil {
+		return defaultEncoder(config.JsonFlag, config.TimeFormat, config.TimeLocation)
+	}
+	return config.LogEncoder
+}
+
+// params : encoder, if nil, the default encoder selected by JsonFlag
+func (config *ConsoleConfig) SetEncoder(encoder Encoder) {
+	config.LogEncoder = encoder
+	config.buildEncoder()
+}
+
+// set time format and time zone of the default encoder
+func (config *ConsoleConfig) SetTimeFormat(timeFormat string, location *time.Location) {
+	config.TimeFormat = timeFormat
+	config.TimeLocation = location
+	config.buildEncoder()
+}
+
+// build the encoder returned by Encoder from the config
+func (config *ConsoleConfig) buildEncoder() {
+	config.encoder.build(config.LogEncoder, config.JsonFlag, config.TimeFormat, config.TimeLocation)
+}
+
 func (config *ConsoleConfig) IsColor() bool {
 	return config.ColorFlag
 }
 
 // adapter console
 type ConsoleAdapter struct {
-	logger *log.Logger
+	lock   sync.Mutex
+	writer io.Writer
 	ConsoleConfig
 	AdapterLogger
 }
@@ -90,31 +132,44 @@ func (*ConsoleAdapter) Name() string {
 }
 
 func (adapterConsole *ConsoleAdapter) Init() error {
+	// build the encoder before messages are written concurrently
+	adapterConsole.buildEncoder()
 	fmt.Printf("[GLOG] > [%s adapter] init success\n", adapterConsole.Name())
 	return nil
 }
 
 func (adapterConsole *ConsoleAdapter) Write(loggerMsg *loggerMsg) error {
 
-	msg := ""
-	if adapterConsole.IsJson() {
-		jsonByte, _ := json.Marshal(loggerMsg)
-		msg = string(jsonByte)
-	} else {
-		msg = formatLoggerMsg(loggerMsg)
+	if !levelEnabled(adapterConsole.Level(), loggerMsg.Ilevel) {
+		return nil
 	}
 
-	if adapterConsole.Level() <= loggerMsg.Ilevel {
-		if adapterConsole.IsColor() {
-			info := TextWithColor(levelColor(loggerMsg.Ilevel), msg)
-			return adapterConsole.logger.Output(2, info)
-		} else {
-			return adapterConsole.logger.Output(2, msg)
-		}
+	buf := GetBuffer()
+	defer buf.Free()
+
+	color := adapterConsole.IsColor()
+	if color {
+		buf.AppendBytes(levelColor(loggerMsg.Ilevel))
+	}
+	if err := adapterConsole.Encoder().Encode(buf, loggerMsg); err != nil {
+		return err
 	}
+	if color {
+		buf.AppendBytes(reset)
+	}
+	buf.AppendByte('\n')
 
-	return nil
+	adapterConsole.lock.Lock()
+	defer adapterConsole.lock.Unlock()
+	if adapterConsole.writer == nil {
+		adapterConsole.writer = os.Stdout
+	}
+	_, err := adapterConsole.writer.Write(buf.Bytes())
+	return err
+}
 
+// console output is not buffered, nothing to flush
+func (adapterConsole *ConsoleAdapter) Flush() {
 }
 
 // interface wrapper function, 返回类型必须是接口类型
@@ -126,7 +181,7 @@ func NewConsoleAdapter(loglevel LOGLEVEL, color bool, json bool) AbstractLogger
 	}
 
 	return &ConsoleAdapter{
-		logger: log.New(os.Stdout, "", 0),
+		writer:        os.Stdout,
 		ConsoleConfig: consoleConfig,
 		AdapterLogger: AdapterLogger{
 			Id: "defaultConsole",
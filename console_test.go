@@ -9,7 +9,7 @@ func TestWriteLogmsg(t *testing.T) {
 	console := NewConsoleAdapter(INFO, true, false)
 
 	console.Write(&loggerMsg{
-		Itime:  time.Now().Format(DashMillisecondFormat),
+		Time:   time.Now(),
 		Ilevel: INFO,
 		File:   "test.go",
 		Line:   17,
@@ -0,0 +1,123 @@
+package glog
+
+import (
+	"context"
+	"sync"
+)
+
+// pull fields such as trace id, span id or tenant id out of a context.Context
+type ContextExtractor func(ctx context.Context) []Field
+
+type namedExtractor struct {
+	name      string
+	extractor ContextExtractor
+}
+
+var (
+	extractorLock     sync.RWMutex
+	contextExtractors []namedExtractor
+)
+
+// RegisterContextExtractor register a context extractor, extractors run in register order
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	extractorLock.Lock()
+	defer extractorLock.Unlock()
+
+	for _, v := range contextExtractors {
+		if v.name == name {
+			panic("logger: context extractor " + name + " already registered!")
+		}
+	}
+	contextExtractors = append(contextExtractors, namedExtractor{name: name, extractor: extractor})
+}
+
+// UnregisterContextExtractor remove a context extractor by name
+func UnregisterContextExtractor(name string) {
+	extractorLock.Lock()
+	defer extractorLock.Unlock()
+
+	for i, v := range contextExtractors {
+		if v.name == name {
+			extractors := make([]namedExtractor, 0, len(contextExtractors)-1)
+			extractors = append(extractors, contextExtractors[:i]...)
+			contextExtractors = append(extractors, contextExtractors[i+1:]...)
+			return
+		}
+	}
+}
+
+// ContextValueExtractor build an extractor which reads ctx.Value(key) into fieldKey
+func ContextValueExtractor(key interface{}, fieldKey string) ContextExtractor {
+	return func(ctx context.Context) []Field {
+		value := ctx.Value(key)
+		if value == nil {
+			return nil
+		}
+		return []Field{{Key: fieldKey, Value: value}}
+	}
+}
+
+// run all registered extractors on ctx
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	extractorLock.RLock()
+	defer extractorLock.RUnlock()
+
+	var fields []Field
+	for _, v := range contextExtractors {
+		fields = append(fields, v.extractor(ctx)...)
+	}
+	return fields
+}
+
+func (logger *Logger) logInternalCtx(ctx context.Context, level LOGLEVEL, msg string, kv ...interface{}) {
+	if !logger.Enabled(level) {
+		return
+	}
+	root := logger.base()
+	fields := logger.mergeFields(contextFields(ctx), kv)
+	root.logInternalWithCaller(level, root.globalTimeFormat, logger.name, msg, fields, root.callerFlag, logger.callerSkip)
+}
+
+func (logger *Logger) FatalCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, FATAL, msg, kv...)
+	logger.exit()
+}
+
+func (logger *Logger) PanicCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, PANIC, msg, kv...)
+	logger.panic(msg)
+}
+
+//log at any level with context fields, see Log
+func (logger *Logger) LogCtx(ctx context.Context, level LOGLEVEL, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, level, msg, kv...)
+	switch level {
+	case FATAL:
+		logger.exit()
+	case PANIC:
+		logger.panic(msg)
+	}
+}
+
+func (logger *Logger) ErrorCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, ERROR, msg, kv...)
+}
+
+func (logger *Logger) WarnCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, WARN, msg, kv...)
+}
+
+func (logger *Logger) InfoCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, INFO, msg, kv...)
+}
+
+func (logger *Logger) DebugCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, DEBUG, msg, kv...)
+}
+
+func (logger *Logger) TraceCtx(ctx context.Context, msg string, kv ...interface{}) {
+	logger.logInternalCtx(ctx, TRACE, msg, kv...)
+}
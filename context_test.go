@@ -0,0 +1,34 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type traceKey struct{}
+
+func TestContextExtractor(t *testing.T) {
+	RegisterContextExtractor("trace", ContextValueExtractor(traceKey{}, "trace_id"))
+	defer UnregisterContextExtractor("trace")
+
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	ctx := context.WithValue(context.Background(), traceKey{}, "abc")
+	logger.InfoCtx(ctx, "handled", "status", 200)
+
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	if res["trace_id"] != "abc" || res["status"] != float64(200) {
+		t.Errorf("unexpected json output: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.InfoCtx(context.Background(), "no trace")
+	if bytes.Contains(buf.Bytes(), []byte("trace_id")) {
+		t.Errorf("unexpected trace_id: %s", buf.String())
+	}
+}
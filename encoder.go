@@ -0,0 +1,611 @@
+package glog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// Encoder format a Message into buf as one line, the trailing newline is added by the adapter
+type Encoder interface {
+	Encode(buf *Buffer, loggerMsg *Message) error
+}
+
+// EncoderFunc adapt a function to Encoder
+type EncoderFunc func(buf *Buffer, loggerMsg *Message) error
+
+func (f EncoderFunc) Encode(buf *Buffer, loggerMsg *Message) error {
+	return f(buf, loggerMsg)
+}
+
+// TextEncoder output "time [level] [logger] [file:line] body key=value"
+type TextEncoder struct {
+	// time layout, if empty, the logger time format is used
+	TimeLayout string
+
+	// time zone of the time, if nil, the time is not converted
+	Location *time.Location
+}
+
+func NewTextEncoder() Encoder {
+	return &TextEncoder{}
+}
+
+func (encoder *TextEncoder) Encode(buf *Buffer, loggerMsg *Message) error {
+	appendMsgTime(buf, loggerMsg, encoder.TimeLayout, encoder.Location)
+	buf.AppendString(" [")
+	level := loggerMsg.Ilevel.LevelString()
+	for i := len(level); i < 5; i++ {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(level)
+	buf.AppendString("] [")
+	if loggerMsg.Name != "" {
+		buf.AppendString(loggerMsg.Name)
+		buf.AppendString("] [")
+	}
+	buf.AppendString(loggerMsg.File)
+	buf.AppendByte(':')
+	buf.AppendInt(int64(loggerMsg.Line))
+	if loggerMsg.Package != "" {
+		buf.AppendByte(' ')
+		buf.AppendString(loggerMsg.Package)
+		buf.AppendByte('.')
+		buf.AppendString(loggerMsg.Func)
+	} else if loggerMsg.Func != "" {
+		buf.AppendByte(' ')
+		buf.AppendString(loggerMsg.Func)
+	}
+	buf.AppendString("] ")
+	buf.AppendString(loggerMsg.Body)
+	appendTextFields(buf, loggerMsg.Fields, "", true)
+	if loggerMsg.Stack != "" {
+		buf.AppendByte('\n')
+		buf.AppendString(loggerMsg.Stack)
+	}
+	return nil
+}
+
+// how JSONEncoder encodes the level
+type LevelEncoding int
+
+const (
+	LevelNumber LevelEncoding = iota // LOGLEVEL as an integer
+	LevelUpper                       // "WARN"
+	LevelLower                       // "warn"
+)
+
+// how JSONEncoder encodes the time
+type TimeEncoding int
+
+const (
+	TimeString       TimeEncoding = iota // formatted by TimeLayout
+	TimeEpochSeconds                     // unix seconds with fraction, ex 1554120000.123
+	TimeEpochMillis                      // unix milliseconds
+)
+
+// key names and value encodings of JSONEncoder, an empty key omits the value
+type JSONEncoderConfig struct {
+	TimeKey    string
+	LevelKey   string
+	LoggerKey  string // omitted when the logger has no name
+	MessageKey string
+	FileKey    string
+	LineKey    string
+
+	// if set, file and line are encoded as "file:line" under CallerKey, FileKey and LineKey are ignored
+	CallerKey string
+
+	// function name and package path, omitted when not recorded, see Logger.SetCallerConfig
+	FunctionKey string
+	PackageKey  string
+
+	// stack trace, omitted when not recorded, see Logger.SetStacktrace
+	StacktraceKey string
+
+	LevelEncoding LevelEncoding
+	TimeEncoding  TimeEncoding
+
+	// time layout for TimeString, if empty, the logger time format is used
+	TimeLayout string
+
+	// time zone of TimeString, if nil, the time is not converted
+	Location *time.Location
+}
+
+// config of the default json output:
+// {"create_time":"...","level":2,"logger":"db","body":"...","file":"x.go","line":1}
+func DefaultJSONEncoderConfig() JSONEncoderConfig {
+	return JSONEncoderConfig{
+		TimeKey:       "create_time",
+		LevelKey:      "level",
+		LoggerKey:     "logger",
+		MessageKey:    "body",
+		FileKey:       "file",
+		LineKey:       "line",
+		FunctionKey:   "func",
+		PackageKey:    "package",
+		StacktraceKey: "stacktrace",
+	}
+}
+
+// JSONEncoder output a json object per message, Fields are flattened into extra keys,
+// a field named like a key written for the message is prefixed, ex Infow("m", "level", "x") gives "fields.level":"x"
+type JSONEncoder struct {
+	config JSONEncoderConfig
+	// keys quoted once with the trailing ':'
+	timeKey, levelKey, loggerKey, messageKey, fileKey, lineKey, callerKey, functionKey, packageKey, stacktraceKey string
+}
+
+func NewJSONEncoder() Encoder {
+	return NewJSONEncoderWithConfig(DefaultJSONEncoderConfig())
+}
+
+// ex, ECS style output:
+//
+//	NewJSONEncoderWithConfig(JSONEncoderConfig{TimeKey: "@timestamp", TimeLayout: time.RFC3339Nano,
+//		LevelKey: "log.level", LevelEncoding: LevelLower, LoggerKey: "log.logger", MessageKey: "message",
+//		FileKey: "log.origin.file.name", LineKey: "log.origin.file.line"})
+func NewJSONEncoderWithConfig(config JSONEncoderConfig) Encoder {
+	encoder := &JSONEncoder{config: config}
+	quote := func(key string) string {
+		if key == "" {
+			return ""
+		}
+		buf := GetBuffer()
+		defer buf.Free()
+		appendJSONString(buf, key)
+		buf.AppendByte(':')
+		return buf.String()
+	}
+	encoder.timeKey = quote(config.TimeKey)
+	encoder.levelKey = quote(config.LevelKey)
+	encoder.loggerKey = quote(config.LoggerKey)
+	encoder.messageKey = quote(config.MessageKey)
+	encoder.fileKey = quote(config.FileKey)
+	encoder.lineKey = quote(config.LineKey)
+	encoder.callerKey = quote(config.CallerKey)
+	encoder.functionKey = quote(config.FunctionKey)
+	encoder.packageKey = quote(config.PackageKey)
+	encoder.stacktraceKey = quote(config.StacktraceKey)
+	return encoder
+}
+
+// key of a field in the output, prefixed if the message itself writes key, ex "logger" only when the logger has a name
+func (encoder *JSONEncoder) fieldKey(key string, loggerMsg *Message) string {
+	config := &encoder.config
+	if key != "" && (key == config.TimeKey || key == config.LevelKey || key == config.MessageKey ||
+		key == config.CallerKey ||
+		config.CallerKey == "" && (key == config.FileKey || key == config.LineKey) ||
+		key == config.LoggerKey && loggerMsg.Name != "" ||
+		key == config.FunctionKey && loggerMsg.Func != "" ||
+		key == config.PackageKey && loggerMsg.Package != "" ||
+		key == config.StacktraceKey && loggerMsg.Stack != "") {
+		return fieldKeyPrefix + key
+	}
+	return key
+}
+
+func (encoder *JSONEncoder) Encode(buf *Buffer, loggerMsg *Message) error {
+	config := &encoder.config
+	buf.AppendByte('{')
+	start := buf.Len()
+	// write ',' before every key except the first one
+	key := func(quotedKey string) {
+		if buf.Len() > start {
+			buf.AppendByte(',')
+		}
+		buf.AppendString(quotedKey)
+	}
+
+	if encoder.timeKey != "" {
+		key(encoder.timeKey)
+		switch config.TimeEncoding {
+		case TimeEpochSeconds:
+			appendEpochSeconds(buf, loggerMsg.Time)
+		case TimeEpochMillis:
+			buf.AppendInt(loggerMsg.Time.UnixNano() / int64(time.Millisecond))
+		default:
+			buf.AppendByte('"')
+			appendMsgTime(buf, loggerMsg, config.TimeLayout, config.Location)
+			buf.AppendByte('"')
+		}
+	}
+	if encoder.levelKey != "" {
+		key(encoder.levelKey)
+		switch config.LevelEncoding {
+		case LevelUpper:
+			appendJSONString(buf, loggerMsg.Ilevel.LevelString())
+		case LevelLower:
+			buf.AppendByte('"')
+			for _, c := range []byte(loggerMsg.Ilevel.LevelString()) {
+				if c >= 'A' && c <= 'Z' {
+					c += 'a' - 'A'
+				}
+				buf.AppendByte(c)
+			}
+			buf.AppendByte('"')
+		default:
+			buf.AppendInt(int64(loggerMsg.Ilevel))
+		}
+	}
+	if encoder.loggerKey != "" && loggerMsg.Name != "" {
+		key(encoder.loggerKey)
+		appendJSONString(buf, loggerMsg.Name)
+	}
+	if encoder.messageKey != "" {
+		key(encoder.messageKey)
+		appendJSONString(buf, loggerMsg.Body)
+	}
+	if encoder.callerKey != "" {
+		key(encoder.callerKey)
+		buf.AppendByte('"')
+		appendJSONEscaped(buf, loggerMsg.File)
+		buf.AppendByte(':')
+		buf.AppendInt(int64(loggerMsg.Line))
+		buf.AppendByte('"')
+	} else {
+		if encoder.fileKey != "" {
+			key(encoder.fileKey)
+			appendJSONString(buf, loggerMsg.File)
+		}
+		if encoder.lineKey != "" {
+			key(encoder.lineKey)
+			buf.AppendInt(int64(loggerMsg.Line))
+		}
+	}
+	if encoder.functionKey != "" && loggerMsg.Func != "" {
+		key(encoder.functionKey)
+		appendJSONString(buf, loggerMsg.Func)
+	}
+	if encoder.packageKey != "" && loggerMsg.Package != "" {
+		key(encoder.packageKey)
+		appendJSONString(buf, loggerMsg.Package)
+	}
+	if encoder.stacktraceKey != "" && loggerMsg.Stack != "" {
+		key(encoder.stacktraceKey)
+		appendJSONString(buf, loggerMsg.Stack)
+	}
+	for _, field := range loggerMsg.Fields {
+		if buf.Len() > start {
+			buf.AppendByte(',')
+		}
+		appendJSONField(buf, encoder.fieldKey(field.Key, loggerMsg), field.Value)
+	}
+	buf.AppendByte('}')
+	return nil
+}
+
+var (
+	defaultTextEncoder = NewTextEncoder()
+	defaultJSONEncoder = NewJSONEncoder()
+)
+
+// encoder used by an adapter which has no Encoder configured
+func defaultEncoder(json bool, timeFormat string, location *time.Location) Encoder {
+	if timeFormat == "" && location == nil {
+		if json {
+			return defaultJSONEncoder
+		}
+		return defaultTextEncoder
+	}
+	if json {
+		config := DefaultJSONEncoderConfig()
+		config.TimeLayout = timeFormat
+		config.Location = location
+		return NewJSONEncoderWithConfig(config)
+	}
+	return &TextEncoder{TimeLayout: timeFormat, Location: location}
+}
+
+// encoder of an adapter, built by Init, SetEncoder and SetTimeFormat, read by Encoder while messages are written
+type adapterEncoder struct {
+	encoder atomic.Value // encoderValue, atomic.Value needs the same type on every Store
+}
+
+type encoderValue struct {
+	Encoder
+}
+
+// return : the built encoder, nil before it is built
+func (cache *adapterEncoder) get() Encoder {
+	value, _ := cache.encoder.Load().(encoderValue)
+	return value.Encoder
+}
+
+// build the configured encoder, or the default one selected by json and the adapter time settings
+func (cache *adapterEncoder) build(encoder Encoder, json bool, timeFormat string, location *time.Location) {
+	if encoder == nil {
+		encoder = defaultEncoder(json, timeFormat, location)
+	}
+	cache.encoder.Store(encoderValue{encoder})
+}
+
+// append the message time converted to location and formatted by layout,
+// an empty layout falls back to the logger time format
+func appendMsgTime(buf *Buffer, loggerMsg *Message, layout string, location *time.Location) {
+	t := loggerMsg.Time
+	if location != nil {
+		t = t.In(location)
+	}
+	if layout == "" {
+		layout = loggerMsg.timeFormat
+	}
+	if layout == "" {
+		layout = DashMillisecondFormat
+	}
+	buf.AppendTime(t, layout)
+}
+
+// append unix seconds with the fraction of nanoseconds, trailing zeros trimmed
+func appendEpochSeconds(buf *Buffer, t time.Time) {
+	buf.AppendInt(t.Unix())
+	nsec := t.Nanosecond()
+	if nsec == 0 {
+		return
+	}
+	var digits [9]byte
+	for i := 8; i >= 0; i-- {
+		digits[i] = byte('0' + nsec%10)
+		nsec /= 10
+	}
+	end := 9
+	for digits[end-1] == '0' {
+		end--
+	}
+	buf.AppendByte('.')
+	buf.AppendBytes(digits[:end])
+}
+
+// append fields as key=value separated by ' ', the fields of a Group get keys joined by '.', ex http.method=GET
+// params : prefix, keys of the enclosing groups, ex "http."
+// params : space, write ' ' before the first field too
+// return : space for the next field, true once a field is written
+func appendTextFields(buf *Buffer, fields []Field, prefix string, space bool) bool {
+	for _, field := range fields {
+		if group, ok := field.Value.(fieldGroup); ok {
+			space = appendTextFields(buf, group, prefix+field.Key+".", space)
+			continue
+		}
+		if space {
+			buf.AppendByte(' ')
+		}
+		space = true
+		buf.AppendString(prefix)
+		buf.AppendString(field.Key)
+		buf.AppendByte('=')
+		appendTextValue(buf, field.Value)
+	}
+	return space
+}
+
+// append a field value in text form, quoted if it contains spaces or quotes
+func appendTextValue(buf *Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		appendTextString(buf, v)
+	case error:
+		appendTextString(buf, v.Error())
+	case bool:
+		buf.AppendBool(v)
+	case int:
+		buf.AppendInt(int64(v))
+	case int8:
+		buf.AppendInt(int64(v))
+	case int16:
+		buf.AppendInt(int64(v))
+	case int32:
+		buf.AppendInt(int64(v))
+	case int64:
+		buf.AppendInt(v)
+	case uint:
+		buf.AppendUint(uint64(v))
+	case uint8:
+		buf.AppendUint(uint64(v))
+	case uint16:
+		buf.AppendUint(uint64(v))
+	case uint32:
+		buf.AppendUint(uint64(v))
+	case uint64:
+		buf.AppendUint(v)
+	case float32:
+		buf.AppendFloat(float64(v), 32)
+	case float64:
+		buf.AppendFloat(v, 64)
+	case time.Time:
+		buf.AppendTime(v, time.RFC3339Nano)
+	case time.Duration:
+		buf.AppendString(v.String())
+	default:
+		appendTextString(buf, fmt.Sprint(v))
+	}
+}
+
+func appendTextString(buf *Buffer, s string) {
+	if needsQuote(s) {
+		buf.AppendQuote(s)
+		return
+	}
+	buf.AppendString(s)
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '"' || c == '=' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// append a field value in json form, errors are encoded by their message
+// append "key":"message", "key_chain":[...] if err wraps other errors
+// and "key_details":"..." if err implements fmt.Formatter and %+v adds something
+func appendJSONError(buf *Buffer, key string, err error) {
+	message := err.Error()
+	appendJSONString(buf, key)
+	buf.AppendByte(':')
+	appendJSONString(buf, message)
+
+	if cause := errors.Unwrap(err); cause != nil {
+		buf.AppendByte(',')
+		appendJSONString(buf, key+"_chain")
+		buf.AppendString(":[")
+		appendJSONString(buf, message)
+		for ; cause != nil; cause = errors.Unwrap(cause) {
+			buf.AppendByte(',')
+			appendJSONString(buf, cause.Error())
+		}
+		buf.AppendByte(']')
+	}
+
+	if _, ok := err.(fmt.Formatter); ok {
+		if details := fmt.Sprintf("%+v", err); details != message {
+			buf.AppendByte(',')
+			appendJSONString(buf, key+"_details")
+			buf.AppendByte(':')
+			appendJSONString(buf, details)
+		}
+	}
+}
+
+// append "key":value, an Err field is expanded, see appendJSONError
+func appendJSONField(buf *Buffer, key string, value interface{}) {
+	if v, ok := value.(errorValue); ok {
+		appendJSONError(buf, key, v.error)
+		return
+	}
+	appendJSONString(buf, key)
+	buf.AppendByte(':')
+	appendJSONValue(buf, value)
+}
+
+func appendJSONValue(buf *Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.AppendString("null")
+	case fieldGroup:
+		buf.AppendByte('{')
+		for i, field := range v {
+			if i > 0 {
+				buf.AppendByte(',')
+			}
+			appendJSONField(buf, field.Key, field.Value)
+		}
+		buf.AppendByte('}')
+	case string:
+		appendJSONString(buf, v)
+	case error:
+		appendJSONString(buf, v.Error())
+	case bool:
+		buf.AppendBool(v)
+	case int:
+		buf.AppendInt(int64(v))
+	case int8:
+		buf.AppendInt(int64(v))
+	case int16:
+		buf.AppendInt(int64(v))
+	case int32:
+		buf.AppendInt(int64(v))
+	case int64:
+		buf.AppendInt(v)
+	case uint:
+		buf.AppendUint(uint64(v))
+	case uint8:
+		buf.AppendUint(uint64(v))
+	case uint16:
+		buf.AppendUint(uint64(v))
+	case uint32:
+		buf.AppendUint(uint64(v))
+	case uint64:
+		buf.AppendUint(v)
+	case float32:
+		appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		appendJSONFloat(buf, v, 64)
+	case time.Time:
+		buf.AppendByte('"')
+		buf.AppendTime(v, time.RFC3339Nano)
+		buf.AppendByte('"')
+	case time.Duration:
+		buf.AppendInt(int64(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			appendJSONString(buf, fmt.Sprint(v))
+			return
+		}
+		buf.AppendBytes(data)
+	}
+}
+
+// NaN and Inf are not valid json numbers, encode them as strings
+func appendJSONFloat(buf *Buffer, f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		buf.AppendByte('"')
+		buf.AppendFloat(f, bitSize)
+		buf.AppendByte('"')
+		return
+	}
+	buf.AppendFloat(f, bitSize)
+}
+
+// append a quoted and escaped json string
+func appendJSONString(buf *Buffer, s string) {
+	buf.AppendByte('"')
+	appendJSONEscaped(buf, s)
+	buf.AppendByte('"')
+}
+
+// append s escaped for a json string, without quotes
+func appendJSONEscaped(buf *Buffer, s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.AppendString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.AppendByte('\\')
+				buf.AppendByte(c)
+			case '\n':
+				buf.AppendString(`\n`)
+			case '\r':
+				buf.AppendString(`\r`)
+			case '\t':
+				buf.AppendString(`\t`)
+			default:
+				buf.AppendString(`\u00`)
+				buf.AppendByte(hexDigits[c>>4])
+				buf.AppendByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.AppendString(s[start:i])
+			buf.AppendString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.AppendString(s[start:])
+}
@@ -0,0 +1,287 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendJSONMsg(t *testing.T) {
+	msg := &loggerMsg{
+		timeFormat: DashMillisecondFormat,
+		Time:       time.Date(2019, 4, 1, 12, 0, 0, 0, time.Local),
+		Ilevel:     WARN,
+		Name:       "db",
+		Body:       "quote \" backslash \\ newline \n ctrl \x01 bad \xff 中文",
+		File:       "test.go",
+		Line:       17,
+		Fields: []Field{
+			Int("n", 2), Float64("f", 1.5), Bool("ok", true), Any("err", errors.New("boom")),
+			Duration("d", time.Second), Any("list", []int{1, 2}), Any("nil", nil),
+		},
+	}
+	buf := GetBuffer()
+	defer buf.Free()
+	NewJSONEncoder().Encode(buf, msg)
+
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	if res["body"] != "quote \" backslash \\ newline \n ctrl \x01 bad � 中文" {
+		t.Errorf("unexpected body: %q", res["body"])
+	}
+	if res["level"] != float64(WARN) || res["logger"] != "db" || res["line"] != float64(17) {
+		t.Errorf("unexpected json output: %s", buf.String())
+	}
+	if res["n"] != float64(2) || res["f"] != 1.5 || res["ok"] != true || res["err"] != "boom" ||
+		res["d"] != float64(time.Second) || len(res["list"].([]interface{})) != 2 || res["nil"] != nil {
+		t.Errorf("unexpected json fields: %s", buf.String())
+	}
+}
+
+func TestAppendTextMsg(t *testing.T) {
+	msg := &loggerMsg{
+		timeFormat: DashMillisecondFormat,
+		Time:       time.Date(2019, 4, 1, 12, 0, 0, 0, time.Local),
+		Ilevel:     INFO,
+		Body:       "hello world",
+		File:       "test.go",
+		Line:       17,
+		Fields:     []Field{String("user", "tony"), String("note", "a b"), String("empty", ""), Int("n", -1)},
+	}
+	want := `2019-04-01 12:00:00.000 [ INFO] [test.go:17] hello world user=tony note="a b" empty="" n=-1`
+	if got := formatLoggerMsg(msg); got != want {
+		t.Errorf("wanted : %s, actual: %s", want, got)
+	}
+}
+
+func newDiscardLogger(json bool) *Logger {
+	console := NewConsoleAdapter(INFO, false, json)
+	console.(*ConsoleAdapter).writer = ioutil.Discard
+	logger, _ := NewLogger(DashMillisecondFormat, false, console)
+	return logger
+}
+
+func TestLoggerAllocs(t *testing.T) {
+	logger := newDiscardLogger(true)
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Info("hello world")
+	})
+	// only the formatted time string is allocated
+	if allocs > 1 {
+		t.Errorf("wanted allocs <= 1, actual: %v", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		logger.Debugf("hello %s", "world")
+	})
+	if allocs > 0 {
+		t.Errorf("disabled level should not allocate, actual: %v", allocs)
+	}
+}
+
+func BenchmarkInfoText(b *testing.B) {
+	logger := newDiscardLogger(false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("hello world")
+	}
+}
+
+func BenchmarkInfoJSON(b *testing.B) {
+	logger := newDiscardLogger(true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("hello world")
+	}
+}
+
+func BenchmarkInfowJSON(b *testing.B) {
+	logger := newDiscardLogger(true).With("request_id", "r-1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Infow("hello world", "user", "tony", "n", i)
+	}
+}
+
+func BenchmarkDebugfDisabled(b *testing.B) {
+	logger := newDiscardLogger(false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("hello %s", "world")
+	}
+}
+
+func BenchmarkInfoParallel(b *testing.B) {
+	logger := newDiscardLogger(false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("hello world")
+		}
+	})
+}
+
+func TestCustomEncoder(t *testing.T) {
+	upper := EncoderFunc(func(buf *Buffer, loggerMsg *Message) error {
+		buf.AppendString(loggerMsg.Ilevel.LevelString())
+		buf.AppendByte('|')
+		buf.AppendString(loggerMsg.Body)
+		return nil
+	})
+
+	console := NewConsoleAdapter(INFO, false, true)
+	console.SetEncoder(upper)
+	out := &bytes.Buffer{}
+	console.(*ConsoleAdapter).writer = out
+
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := NewFileAdapter(INFO, dir, "encoder.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.SetEncoder(upper)
+
+	logger, err := NewLogger(DashMillisecondFormat, false, console, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Warn("custom")
+	logger.Flush()
+
+	if out.String() != "WARN|custom\n" {
+		t.Errorf("unexpected console output: %q", out.String())
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "encoder.log"))
+	if string(data) != "WARN|custom\n" {
+		t.Errorf("unexpected file output: %q", data)
+	}
+}
+
+func TestJSONEncoderConfig(t *testing.T) {
+	msg := &Message{
+		timeFormat: DashMillisecondFormat,
+		Time:       time.Date(2019, 4, 1, 12, 0, 0, 123e6, time.UTC),
+		Ilevel:     WARN,
+		Body:       "slow",
+		File:       "db.go",
+		Line:       42,
+		Fields:     []Field{Int("ms", 120)},
+	}
+
+	cases := []struct {
+		config JSONEncoderConfig
+		want   string
+	}{
+		{DefaultJSONEncoderConfig(),
+			`{"create_time":"2019-04-01 12:00:00.123","level":3,"body":"slow","file":"db.go","line":42,"ms":120}`},
+		{JSONEncoderConfig{TimeKey: "ts", TimeEncoding: TimeEpochMillis, LevelKey: "severity", LevelEncoding: LevelUpper,
+			MessageKey: "msg", CallerKey: "caller"},
+			`{"ts":1554120000123,"severity":"WARN","msg":"slow","caller":"db.go:42","ms":120}`},
+		{JSONEncoderConfig{TimeKey: "@timestamp", TimeLayout: time.RFC3339Nano, LevelKey: "log.level", LevelEncoding: LevelLower,
+			MessageKey: "message"},
+			`{"@timestamp":"2019-04-01T12:00:00.123Z","log.level":"warn","message":"slow","ms":120}`},
+		{JSONEncoderConfig{TimeKey: "time", TimeEncoding: TimeEpochSeconds},
+			`{"time":1554120000.123,"ms":120}`},
+	}
+	for _, c := range cases {
+		buf := GetBuffer()
+		NewJSONEncoderWithConfig(c.config).Encode(buf, msg)
+		if buf.String() != c.want {
+			t.Errorf("wanted : %s, actual: %s", c.want, buf.String())
+		}
+		buf.Free()
+	}
+}
+
+func TestFieldKeyCollision(t *testing.T) {
+	msg := &Message{
+		Ilevel: WARN,
+		Body:   "slow",
+		File:   "db.go",
+		Line:   42,
+		Fields: []Field{String("level", "x"), String("caller", "y"), Err(errors.New("boom")), String("file", "z")},
+	}
+
+	cases := []struct {
+		config JSONEncoderConfig
+		want   string
+	}{
+		{JSONEncoderConfig{LevelKey: "level", MessageKey: "msg", CallerKey: "caller", FileKey: "file"},
+			`{"level":3,"msg":"slow","caller":"db.go:42","fields.level":"x","fields.caller":"y","error":"boom","file":"z"}`},
+		{JSONEncoderConfig{MessageKey: "error", FileKey: "file"},
+			`{"error":"slow","file":"db.go","level":"x","caller":"y","fields.error":"boom","fields.file":"z"}`},
+	}
+	for _, c := range cases {
+		buf := GetBuffer()
+		NewJSONEncoderWithConfig(c.config).Encode(buf, msg)
+		if buf.String() != c.want {
+			t.Errorf("wanted : %s, actual: %s", c.want, buf.String())
+		}
+		buf.Free()
+	}
+}
+
+// an error with extra %+v output, like github.com/pkg/errors
+type detailedError struct {
+	msg    string
+	detail string
+}
+
+func (e *detailedError) Error() string {
+	return e.msg
+}
+
+func (e *detailedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%s", e.msg, e.detail)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func TestErrField(t *testing.T) {
+	root := &detailedError{msg: "connection refused", detail: "dial.go:12"}
+	err := fmt.Errorf("query users: %w", fmt.Errorf("connect: %w", root))
+	msg := &Message{
+		timeFormat: DashMillisecondFormat,
+		Ilevel:     ERROR,
+		Body:       "failed",
+		Fields:     []Field{Err(err), NamedErr("cause", root), Err(nil)},
+	}
+
+	buf := GetBuffer()
+	defer buf.Free()
+	NewJSONEncoderWithConfig(JSONEncoderConfig{MessageKey: "msg"}).Encode(buf, msg)
+	want := `{"msg":"failed",` +
+		`"error":"query users: connect: connection refused",` +
+		`"error_chain":["query users: connect: connection refused","connect: connection refused","connection refused"],` +
+		`"cause":"connection refused","cause_details":"connection refused\ndial.go:12",` +
+		`"error":null}`
+	if buf.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, buf.String())
+	}
+
+	buf.Reset()
+	msg.Fields = fieldsFromKV([]interface{}{errors.New("boom")})
+	NewTextEncoder().Encode(buf, msg)
+	if want := `failed error=boom`; !bytes.HasSuffix(buf.Bytes(), []byte(want)) {
+		t.Errorf("wanted suffix : %s, actual: %s", want, buf.String())
+	}
+}
@@ -0,0 +1,138 @@
+package glog
+
+import (
+	"time"
+)
+
+// a typed key/value pair attached to loggerMsg
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// key used when a kv list has a value without a key
+const badKey = "!BADKEY"
+
+// prefix of a field key which is also a key written for the message, ex "fields.level", so the output has no duplicate keys
+const fieldKeyPrefix = "fields."
+
+// key of the field created by Err
+const errorKey = "error"
+
+// an error recorded by Err, json output expands it into
+// "error", "error_chain" (messages of the errors.Unwrap chain) and "error_details" (%+v)
+type errorValue struct {
+	error
+}
+
+// fields of a Group
+type fieldGroup []Field
+
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// fields nested under key, ex Group("http", String("method", "GET")),
+// encoded as a json object {"http":{"method":"GET"}}, in text output as http.method=GET
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, Value: fieldGroup(fields)}
+}
+
+// record err under the "error" key, ex logger.Errorw("query failed", Err(err))
+func Err(err error) Field {
+	return NamedErr(errorKey, err)
+}
+
+// record err under key, the chain and details keys are prefixed by key
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{Key: key, Value: nil}
+	}
+	return Field{Key: key, Value: errorValue{err}}
+}
+
+// convert a kv list to fields
+// params : kv, every item is a Field, an error recorded as Err(err), or a key followed by its value
+// return : fields
+func fieldsFromKV(kv []interface{}) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, len(kv)/2+1)
+	for i := 0; i < len(kv); i++ {
+		switch v := kv[i].(type) {
+		case Field:
+			fields = append(fields, v)
+		case string:
+			if i+1 >= len(kv) {
+				fields = append(fields, Field{Key: badKey, Value: v})
+				break
+			}
+			fields = append(fields, Field{Key: v, Value: kv[i+1]})
+			i++
+		case error:
+			fields = append(fields, Err(v))
+		default:
+			fields = append(fields, Field{Key: badKey, Value: v})
+		}
+	}
+	return fields
+}
+
+// append more to fields, a field replaces the earlier field with the same key, so the output has no duplicate keys
+// fields is never modified, it may be shared by child loggers
+// return : merged fields, fields itself if more is empty
+func appendFields(fields []Field, more []Field) []Field {
+	if len(more) == 0 {
+		return fields
+	}
+	merged := make([]Field, len(fields), len(fields)+len(more))
+	copy(merged, fields)
+	for _, field := range more {
+		replaced := false
+		// values without a key are all kept
+		for i := range merged {
+			if field.Key != badKey && merged[i].Key == field.Key {
+				merged[i] = field
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, field)
+		}
+	}
+	return merged
+}
@@ -1,11 +1,12 @@
 package glog
 
 import (
-	"encoding/json"
-	"errors"
+	"bytes"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,127 +26,143 @@ const (
 	FILE_SLICE_DATE_HOUR
 )
 
+// bytes, ex MaxSize: 500 * MB
 const (
-	KB UNIT = 1 << (iota * 10) // 2^0, 2^10, 2^20, 2^30
+	KB UNIT = 1 << ((iota + 1) * 10) // 2^10, 2^20, 2^30, 2^40
 	MB
 	GB
 	TB
 )
 
+// RollingDaily also rotates by size or line within the period when MaxSize or MaxLine is set,
+// ex RollingType: RollingDaily, DateSlice: FILE_SLICE_DATE_DAY, MaxSize: 500 * MB
 const (
 	RollingDaily ROLLTYPE = iota
 	RollingFileSize
 	RollingFileLine
 )
 
+// time layouts of rotated file names by DateSlice
+var dateSliceFormats = map[SliceDateType]string{
+	FILE_SLICE_DATE_YEAR:  "2006",
+	FILE_SLICE_DATE_MONTH: "200601",
+	FILE_SLICE_DATE_DAY:   "20060102",
+	FILE_SLICE_DATE_HOUR:  "2006010215",
+}
+
 // file fileWriter
 type FileWriter struct {
 	lock      sync.RWMutex
 	writer    *os.File
 	startLine int64
+	size      int64 // bytes of the log file, counted by writes instead of os.Stat
 	startTime int64
-	logfile   string //log file , absolute path
+	logfile   string       //log file , absolute path
+	cleaner   *fileCleaner // compresses and deletes rotated files, nil without Compressor and retention options
+	clock     TimerClock   // time source of file start time and rotation
+	timer     Timer        // next scheduled rotation, nil without RollingDaily
+	closed    bool
 }
 
-//slice file by date (y, m, d, h), rename file is file_time.log and recreate file
-func (fw *FileWriter) sliceByDate(dateSlice SliceDateType) error {
-
-	filename := fw.logfile
-	filenameSuffix := path.Ext(filename)
-	startTime := time.Unix(fw.startTime, 0)
-	nowTime := time.Now()
-
-	oldFilename := ""
-	isHaveSlice := false
-	if (dateSlice == FILE_SLICE_DATE_YEAR) &&
-		(startTime.Year() != nowTime.Year()) {
-		isHaveSlice = true
-		oldFilename = strings.Replace(filename, filenameSuffix, "", 1) + "_" + startTime.Format("2006") + filenameSuffix
-	}
-	if (dateSlice == FILE_SLICE_DATE_MONTH) &&
-		(startTime.Format("200601") != nowTime.Format("200601")) {
-		isHaveSlice = true
-		oldFilename = strings.Replace(filename, filenameSuffix, "", 1) + "_" + startTime.Format("200601") + filenameSuffix
-	}
-	if (dateSlice == FILE_SLICE_DATE_DAY) &&
-		(startTime.Format("20060102") != nowTime.Format("20060102")) {
-		isHaveSlice = true
-		oldFilename = strings.Replace(filename, filenameSuffix, "", 1) + "_" + startTime.Format("20060102") + filenameSuffix
-	}
-	if (dateSlice == FILE_SLICE_DATE_HOUR) &&
-		(startTime.Format("2006010215") != startTime.Format("2006010215")) {
-		isHaveSlice = true
-		oldFilename = strings.Replace(filename, filenameSuffix, "", 1) + "_" + startTime.Format("2006010215") + filenameSuffix
+//name of a rotated file, a rotated file is never overwritten
+//RollingDaily : file_20261017.log
+//RollingDaily with MaxSize or MaxLine : file_20261017.1.log, file_20261017.2.log, ...
+//RollingFileSize or RollingFileLine : file.2026-10-17-15.04.05.1.log, the time of rotation with an index
+//the date is the start of the file period, formatted with minutes when RotateInterval is set, ex file_202610171415.log
+func (fw *FileWriter) rotatedFilename(config *FileConfig) string {
+	filenameSuffix := path.Ext(fw.logfile)
+	prefix := strings.TrimSuffix(fw.logfile, filenameSuffix)
+
+	var stem string
+	if !config.rollingByDate() {
+		stem = prefix + "." + fw.clock.Now().Format("2006-01-02-15.04.05")
+	} else {
+		startTime := time.Unix(fw.startTime, 0).In(config.rotateLocation())
+		if config.RotateInterval > 0 {
+			startTime = rotateIntervalStart(startTime, config)
+		}
+		stem = prefix + "_" + startTime.Format(config.rotateFormat())
+		if !config.rollingBySize() && !config.rollingByLine() {
+			if matches, _ := filepath.Glob(stem + filenameSuffix + "*"); len(matches) == 0 {
+				return stem + filenameSuffix
+			}
+		}
 	}
 
-	if isHaveSlice == true {
-		//close file handler
-		fw.writer.Close()
-		err := os.Rename(fw.logfile, oldFilename)
-		if err != nil {
-			return err
+	// next index after the rotated files of the same stem, compressed or not
+	index := 0
+	matches, _ := filepath.Glob(stem + ".*")
+	for _, match := range matches {
+		indexFlag := strings.TrimPrefix(match, stem+".")
+		if i := strings.IndexByte(indexFlag, '.'); i >= 0 {
+			indexFlag = indexFlag[:i]
 		}
-		err = fw.initFile()
-		if err != nil {
-			return err
+		if n, err := strconv.Atoi(indexFlag); err == nil && n > index {
+			index = n
 		}
 	}
-
-	return nil
+	return fmt.Sprintf("%s.%d%s", stem, index+1, filenameSuffix)
 }
 
-//slice file by size, if maxSize < fileSize, rename file is file_size_maxSize_time.log and recreate file
-func (fw *FileWriter) sliceByFileSize(maxSize int64) error {
-
-	filename := fw.logfile
-	filenameSuffix := path.Ext(filename)
-	nowSize, _ := fw.getFileSize(filename)
+//close the log file, rename it and recreate it
+func (fw *FileWriter) rotate(config *FileConfig) error {
+	oldFilename := fw.rotatedFilename(config)
 
-	if nowSize >= maxSize {
-		//close file handle
-		fw.writer.Close()
-		timeFlag := time.Now().Format("2006-01-02-15.04.05.9999")
-		oldFilename := strings.Replace(filename, filenameSuffix, "", 1) + "." + timeFlag + filenameSuffix
-		err := os.Rename(filename, oldFilename)
-		if err != nil {
-			return err
-		}
-		err = fw.initFile()
-		if err != nil {
-			return err
+	//close file handler
+	fw.writer.Close()
+	err := os.Rename(fw.logfile, oldFilename)
+	if err != nil {
+		// keep writing to the log file, the next rotation tries again
+		if initErr := fw.initFile(); initErr != nil {
+			return fmt.Errorf("%w, reopen: %v", err, initErr)
 		}
+		return err
 	}
+	if fw.cleaner != nil {
+		fw.cleaner.trigger()
+	}
+	return fw.initFile()
+}
 
+//slice file by size before the file grows over maxSize,
+//a message larger than maxSize is still written into a file alone
+func (fw *FileWriter) sliceByFileSize(config *FileConfig, msgSize int) error {
+	if fw.size > 0 && fw.size+int64(msgSize) > int64(config.MaxSize) {
+		return fw.rotate(config)
+	}
 	return nil
 }
 
-//slice file by line, if maxLine < fileLine, rename file is file_line_maxLine_time.log and recreate file
-func (fw *FileWriter) sliceByFileLine(maxLine int64) error {
-
-	filename := fw.logfile
-	filenameSuffix := path.Ext(filename)
-	startLine := fw.startLine
-
-	if startLine >= maxLine {
-		//close file handle
-		fw.writer.Close()
-		timeFlag := time.Now().Format("2006-01-02-15.04.05.9999")
-		oldFilename := strings.Replace(filename, filenameSuffix, "", 1) + "." + timeFlag + filenameSuffix
-		err := os.Rename(filename, oldFilename)
-		if err != nil {
-			return err
-		}
-		err = fw.initFile()
-		if err != nil {
-			return err
-		}
+//slice file by line, if maxLine <= fileLine
+func (fw *FileWriter) sliceByFileLine(config *FileConfig) error {
+	if fw.startLine >= config.MaxLine {
+		return fw.rotate(config)
 	}
-
 	return nil
 }
 
 func (fw *FileWriter) flush() {
-	fw.writer.Close()
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	fw.writer.Sync()
+}
+
+// stop the scheduler and the cleaner, then close the log file
+func (fw *FileWriter) close() error {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	fw.closed = true
+	if fw.timer != nil {
+		fw.timer.Stop()
+		fw.timer = nil
+	}
+	if fw.cleaner != nil {
+		fw.cleaner.stop()
+		fw.cleaner = nil
+	}
+	return fw.writer.Close()
 }
 
 // init file
@@ -159,8 +176,17 @@ func (fw *FileWriter) initFile() error {
 	}
 	fw.writer = fp
 
-	// get start time
-	fw.startTime = time.Now().Unix()
+	info, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	fw.size = info.Size()
+
+	// get start time, a file written before belongs to the period of its last write
+	fw.startTime = fw.clock.Now().Unix()
+	if info.Size() > 0 && info.ModTime().Unix() < fw.startTime {
+		fw.startTime = info.ModTime().Unix()
+	}
 
 	// get file start lines
 	nowLines, err := FileLines(fw.logfile)
@@ -172,72 +198,49 @@ func (fw *FileWriter) initFile() error {
 	return nil
 }
 
-//get file size
-//params : logfile
-//return : fileSize(byte int64), error
-func (fw *FileWriter) getFileSize(filename string) (int64, error) {
-	fileInfo, err := os.Stat(filename)
-	if err != nil {
-		return 0, err
-	}
-
-	return fileInfo.Size() / 1024, nil
-}
-
 // writers by config
-func (fw *FileWriter) writeByConfig(config *FileConfig, loggerMsg *loggerMsg) error {
+func (fw *FileWriter) writeByConfig(config *FileConfig, msg []byte) error {
 
 	fw.lock.Lock()
 	defer fw.lock.Unlock()
 
-	if config.RollingType == RollingDaily {
-		// file slice by date
-		err := fw.sliceByDate(config.DateSlice)
-		if err != nil {
-			return err
-		}
-	}
-	if config.RollingType == RollingFileLine {
+	// file slice by date is done by the scheduler, see scheduleRotation
+	if config.rollingByLine() {
 		// file slice by line
-		err := fw.sliceByFileLine(config.MaxLine)
+		err := fw.sliceByFileLine(config)
 		if err != nil {
 			return err
 		}
 	}
-	if config.RollingType == RollingFileSize {
+	if config.rollingBySize() {
 		// file slice by size
-		err := fw.sliceByFileSize(config.MaxSize)
+		err := fw.sliceByFileSize(config, len(msg))
 		if err != nil {
 			return err
 		}
 	}
 
-	msg := ""
-	if config.JsonFlag == true {
-		jsonByte, _ := json.Marshal(loggerMsg)
-		msg = string(jsonByte) + "\n"
-	} else {
-		msg = formatLoggerMsg(loggerMsg) + "\n"
-	}
-
-	if config.Level() <= loggerMsg.Ilevel {
-		fw.writer.Write([]byte(msg))
-		if config.MaxLine != 0 {
-			if config.JsonFlag == true {
-				fw.startLine += 1
-			} else {
-				fw.startLine += int64(strings.Count(msg, "\n"))
-			}
-		}
+	n, err := fw.writer.Write(msg)
+	fw.size += int64(n)
+	if config.MaxLine != 0 {
+		fw.startLine += int64(bytes.Count(msg, []byte{'\n'}))
 	}
 
-	return nil
+	return err
 }
 
 type FileConfig struct {
 	// use json format to output
 	JsonFlag bool
 
+	// encoder of messages, if nil, selected by JsonFlag
+	LogEncoder Encoder
+
+	// time format and time zone of the default encoder,
+	// if TimeFormat is empty, the logger time format is used, if TimeLocation is nil, local time
+	TimeFormat   string
+	TimeLocation *time.Location
+
 	LogLevel LOGLEVEL
 
 	// log store dir
@@ -246,12 +249,15 @@ type FileConfig struct {
 	// log logfile
 	Filename string
 
+	// RollingDaily, the default, RollingFileSize or RollingFileLine
 	RollingType ROLLTYPE
 
-	// max file size
-	MaxSize int64
+	// max file size in bytes, ex 500 * MB, required by RollingFileSize,
+	// with RollingDaily, the file is also rotated whenever it exceeds MaxSize within the period
+	MaxSize UNIT
 
-	// max file line
+	// max file line, required by RollingFileLine,
+	// with RollingDaily, the file is also rotated whenever it exceeds MaxLine within the period
 	MaxLine int64
 
 	// file slice by date
@@ -261,42 +267,117 @@ type FileConfig struct {
 	// "h" Log files are cut through hour
 	DateSlice SliceDateType
 
+	// rotate every interval instead of by DateSlice, ex 15 * time.Minute,
+	// boundaries are aligned to midnight, at most 24 hours
+	RotateInterval time.Duration
+
+	// time zone of rotation boundaries and rotated file names, if nil, TimeLocation, then local time
+	RotateLocation *time.Location
+
+	// time source of rotation, inject a fake clock in tests, if nil, the system clock
+	Clock TimerClock
+
+	// retention of rotated files, zero means no limit, files are deleted oldest first in background
+	// only files named like the rotated files of Filename are deleted
+	MaxBackups   int           // max number of rotated files
+	MaxAge       time.Duration // max age of rotated files by modification time, measured by Clock
+	MaxTotalSize UNIT          // max bytes of rotated files and the log file together, ex 10 * GB
+
+	// compress rotated files in background, ex GzipCompressor{}, if nil, rotated files are kept as is
+	Compressor Compressor
+
 	LoggerConfig
+
+	level   adapterLevel
+	encoder adapterEncoder
 }
 
 func (config *FileConfig) Level() LOGLEVEL {
-	return config.LogLevel
+	return config.level.get(config.LogLevel)
 }
 
+// safe while messages are written, Level() returns loglevel from now on
 func (config *FileConfig) SetLevel(loglevel LOGLEVEL) {
-	config.LogLevel = loglevel
+	config.level.set(loglevel)
 }
 
 func (config *FileConfig) IsJson() bool {
 	return config.JsonFlag
 }
 
+// the encoder built by Init, SetEncoder or SetTimeFormat, safe while they run
+func (config *FileConfig) Encoder() Encoder {
+	if encoder := config.encoder.get(); encoder != nil {
+		return encoder
+	}
+	if config.LogEncoder == nil {
+		return defaultEncoder(config.JsonFlag, config.TimeFormat, config.TimeLocation)
+	}
+	return config.LogEncoder
+}
+
+// params : encoder, if nil, the default encoder selected by JsonFlag
+func (config *FileConfig) SetEncoder(encoder Encoder) {
+	config.LogEncoder = encoder
+	config.buildEncoder()
+}
+
+// set time format and time zone of the default encoder
+func (config *FileConfig) SetTimeFormat(timeFormat string, location *time.Location) {
+	config.TimeFormat = timeFormat
+	config.TimeLocation = location
+	config.buildEncoder()
+}
+
+// build the encoder returned by Encoder from the config
+func (config *FileConfig) buildEncoder() {
+	config.encoder.build(config.LogEncoder, config.JsonFlag, config.TimeFormat, config.TimeLocation)
+}
+
+// whether the file is rotated at period boundaries
+func (config *FileConfig) rollingByDate() bool {
+	return config.RollingType == RollingDaily
+}
+
+// whether the file is rotated when it would exceed MaxSize, alone or within the period of RollingDaily
+func (config *FileConfig) rollingBySize() bool {
+	return config.RollingType == RollingFileSize || config.RollingType == RollingDaily && config.MaxSize > 0
+}
+
+// whether the file is rotated when it reaches MaxLine, alone or within the period of RollingDaily
+func (config *FileConfig) rollingByLine() bool {
+	return config.RollingType == RollingFileLine || config.RollingType == RollingDaily && config.MaxLine > 0
+}
+
 func (config *FileConfig) CheckConfig() error {
 	if config.FilePath == "" || config.Filename == "" {
-		return errors.New("config FilePath and Filename can't be empty")
+		return fmt.Errorf("%w: config FilePath and Filename can't be empty", ErrInvalidConfig)
 	}
 
 	switch config.RollingType {
 	case RollingDaily:
-		if config.DateSlice == FILE_SLICE_DATE_NULL {
-			return errors.New("when RollingType is RollingDaily, must config DateSlice")
+		if _, ok := dateSliceFormats[config.DateSlice]; !ok && config.RotateInterval == 0 {
+			return fmt.Errorf("%w: when RollingType is RollingDaily, must config DateSlice or RotateInterval", ErrInvalidConfig)
 		}
 	case RollingFileSize:
 		if config.MaxSize == 0 {
-			return errors.New("when RollingType is RollingFileSize, must config MaxSize")
+			return fmt.Errorf("%w: when RollingType is RollingFileSize, must config MaxSize", ErrInvalidConfig)
 		}
-
 	case RollingFileLine:
 		if config.MaxLine == 0 {
-			return errors.New("when RollingType is RollingFileLine, must config MaxLine")
+			return fmt.Errorf("%w: when RollingType is RollingFileLine, must config MaxLine", ErrInvalidConfig)
 		}
 	default:
-		return errors.New("must config RollingType")
+		return fmt.Errorf("%w: must config RollingType", ErrInvalidConfig)
+	}
+	if config.RotateInterval < 0 || config.RotateInterval > 24*time.Hour {
+		return fmt.Errorf("%w: RotateInterval must be in (0, 24h]", ErrInvalidConfig)
+	}
+	if config.MaxSize < 0 || config.MaxLine < 0 {
+		return fmt.Errorf("%w: MaxSize and MaxLine can't be negative", ErrInvalidConfig)
+	}
+	if config.MaxBackups < 0 || config.MaxAge < 0 || config.MaxTotalSize < 0 {
+		return fmt.Errorf("%w: MaxBackups, MaxAge and MaxTotalSize can't be negative", ErrInvalidConfig)
 	}
 	return nil
 }
@@ -313,7 +394,29 @@ func (*FileAdapter) Name() string {
 }
 
 func (adapterFile *FileAdapter) Init() error {
-	adapterFile.CheckConfig()
+	if err := adapterFile.CheckConfig(); err != nil {
+		return err
+	}
+	// adapters created by Register have no writer yet
+	if adapterFile.fileWriter == nil {
+		fileWriter, err := newFileWriter(path.Join(adapterFile.FilePath, adapterFile.Filename), adapterFile.Clock)
+		if err != nil {
+			return err
+		}
+		adapterFile.fileWriter = fileWriter
+	}
+	fw := adapterFile.fileWriter
+	if fw.timer == nil && adapterFile.rollingByDate() {
+		fw.startScheduler(&adapterFile.FileConfig)
+	}
+	if fw.cleaner == nil && (adapterFile.MaxBackups > 0 || adapterFile.MaxAge > 0 || adapterFile.MaxTotalSize > 0 ||
+		adapterFile.Compressor != nil) {
+		fw.cleaner = newFileCleaner(fw.logfile, &adapterFile.FileConfig)
+		go fw.cleaner.run()
+		fw.cleaner.trigger()
+	}
+	// build the encoder before messages are written concurrently
+	adapterFile.buildEncoder()
 	fmt.Printf("[GLOG] > [%s adapter] init success\n", adapterFile.Name())
 	return nil
 }
@@ -321,24 +424,18 @@ func (adapterFile *FileAdapter) Init() error {
 // Write
 func (adapterFile *FileAdapter) Write(loggerMsg *loggerMsg) error {
 
-	var accessChan = make(chan error, 1)
-
-	go func() {
-		err := adapterFile.fileWriter.writeByConfig(&adapterFile.FileConfig, loggerMsg)
-		if err != nil {
-			accessChan <- err
-			return
-		}
-		accessChan <- nil
-	}()
-
-	var accessErr error
-	accessErr = <-accessChan
+	if !levelEnabled(adapterFile.Level(), loggerMsg.Ilevel) {
+		return nil
+	}
 
-	if accessErr != nil {
-		return accessErr.(error)
+	buf := GetBuffer()
+	defer buf.Free()
+	if err := adapterFile.Encoder().Encode(buf, loggerMsg); err != nil {
+		return err
 	}
-	return nil
+	buf.AppendByte('\n')
+
+	return adapterFile.fileWriter.writeByConfig(&adapterFile.FileConfig, buf.Bytes())
 }
 
 // Flush
@@ -346,37 +443,63 @@ func (adapterFile *FileAdapter) Flush() {
 	adapterFile.fileWriter.flush()
 }
 
-func NewFileWriter(filepath, filename string) *FileWriter {
+// stop background work and close the log file, detach the adapter before Close
+func (adapterFile *FileAdapter) Close() error {
+	return adapterFile.fileWriter.close()
+}
+
+func NewFileWriter(filepath, filename string) (*FileWriter, error) {
+	return newFileWriter(path.Join(filepath, filename), nil)
+}
+
+// params : clock, if nil, the system clock
+func newFileWriter(logfile string, clock TimerClock) (*FileWriter, error) {
+	if clock == nil {
+		clock = systemClock{}
+	}
 	fw := &FileWriter{
-		logfile: path.Join(filepath, filename),
+		logfile: logfile,
+		clock:   clock,
 	}
-	fw.initFile()
-	return fw
-
+	if err := fw.initFile(); err != nil {
+		return nil, err
+	}
+	return fw, nil
 }
 
-func NewFileAdapter(loglevel LOGLEVEL, filepath string, filename string) AbstractLogger {
-	fileConfig := FileConfig{
+// new file adapter rotating daily
+func NewFileAdapter(loglevel LOGLEVEL, filepath string, filename string) (AbstractLogger, error) {
+	return NewFileAdapterWithConfig(FileConfig{
 		FilePath:    filepath,
 		Filename:    filename,
 		JsonFlag:    false,
 		LogLevel:    loglevel,
 		RollingType: RollingDaily,
 		DateSlice:   FILE_SLICE_DATE_DAY,
-	}
+	})
+}
+
+// new file adapter, ex rotate daily and every 500MB within a day:
+//
+//	NewFileAdapterWithConfig(FileConfig{FilePath: "/var/log/app", Filename: "app.log", LogLevel: INFO,
+//		RollingType: RollingDaily, DateSlice: FILE_SLICE_DATE_DAY, MaxSize: 500 * MB})
+func NewFileAdapterWithConfig(fileConfig FileConfig) (AbstractLogger, error) {
 	err := fileConfig.CheckConfig()
 	if err != nil {
-		printError("file config illegal : %s", err.Error())
+		return nil, err
 	}
 
-	fileWriter := NewFileWriter(fileConfig.FilePath, fileConfig.Filename)
+	fileWriter, err := newFileWriter(path.Join(fileConfig.FilePath, fileConfig.Filename), fileConfig.Clock)
+	if err != nil {
+		return nil, err
+	}
 	return &FileAdapter{
 		fileWriter: fileWriter,
 		FileConfig: fileConfig,
 		AdapterLogger: AdapterLogger{
 			Id: "defaultFile",
 		},
-	}
+	}, nil
 }
 
 func init() {
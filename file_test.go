@@ -0,0 +1,322 @@
+package glog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// names of the files in dir
+func dirFiles(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedCopy(names []string) []string {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	return names
+}
+
+// TimerClock moved by Advance, timers fire synchronously in Advance
+type fakeTimerClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (timer *fakeTimer) Stop() bool {
+	stopped := timer.stopped
+	timer.stopped = true
+	return !stopped
+}
+
+func (clock *fakeTimerClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+func (clock *fakeTimerClock) AfterFunc(d time.Duration, f func()) Timer {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	timer := &fakeTimer{at: clock.now.Add(d), f: f}
+	clock.timers = append(clock.timers, timer)
+	return timer
+}
+
+// move the clock, firing due timers in order
+func (clock *fakeTimerClock) Advance(d time.Duration) {
+	clock.lock.Lock()
+	end := clock.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, timer := range clock.timers {
+			if !timer.stopped && !timer.at.After(end) && (next == nil || timer.at.Before(next.at)) {
+				next = timer
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.stopped = true
+		clock.now = next.at
+		clock.lock.Unlock()
+		next.f()
+		clock.lock.Lock()
+	}
+	clock.now = end
+	clock.lock.Unlock()
+}
+
+// a file adapter writing app.log in a temp dir
+func newTestFileAdapter(t *testing.T, config FileConfig) (*FileAdapter, *Logger, string) {
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestFileAdapterIn(t, dir, config)
+}
+
+func newTestFileAdapterIn(t *testing.T, dir string, config FileConfig) (*FileAdapter, *Logger, string) {
+	config.FilePath = dir
+	config.Filename = "app.log"
+	config.LogLevel = INFO
+	if config.RotateLocation == nil {
+		config.RotateLocation = testZone
+	}
+	adapter, err := NewFileAdapterWithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewLogger(DashMillisecondFormat, false, adapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adapter.(*FileAdapter), logger, dir
+}
+
+var testZone = time.FixedZone("UTC+8", 8*3600)
+
+func checkDirFiles(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	want = sortedCopy(want)
+	if got := dirFiles(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("wanted : %v, actual: %v", want, got)
+	}
+}
+
+func TestCompositeRotation(t *testing.T) {
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, logger, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_DAY,
+		MaxLine:     2,
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+	defer adapter.Close()
+
+	for i := 0; i < 5; i++ {
+		logger.Info("line")
+	}
+	checkDirFiles(t, dir, "app.log", "app_20261017.1.log", "app_20261017.2.log")
+
+	// the day is over, the current file gets the next index of its own day
+	clock.Advance(14 * time.Hour)
+	checkDirFiles(t, dir, "app.log", "app_20261017.1.log", "app_20261017.2.log", "app_20261017.3.log")
+
+	logger.Info("line")
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if strings.Count(string(data), "\n") != 1 {
+		t.Errorf("wanted 1 line in the new file, actual: %q", data)
+	}
+}
+
+func TestRotationRenameFailed(t *testing.T) {
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, logger, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_DAY,
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+	defer adapter.Close()
+
+	// the rename of the scheduled rotation fails, the log file is reopened
+	logger.Info("before")
+	if err := os.Remove(filepath.Join(dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(14 * time.Hour)
+	if err := adapter.Write(&Message{Ilevel: INFO, Body: "after"}); err != nil {
+		t.Fatalf("write after a failed rotation: %v", err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if !strings.Contains(string(data), "after") {
+		t.Errorf("wanted the message in the reopened file, actual: %q", data)
+	}
+}
+
+func TestScheduledRotation(t *testing.T) {
+	cases := []struct {
+		config FileConfig
+		start  time.Time
+		want   []string // rotated files after advancing 1, 2 and 3 periods
+	}{
+		{FileConfig{DateSlice: FILE_SLICE_DATE_HOUR}, time.Date(2026, 10, 17, 22, 30, 0, 0, testZone),
+			[]string{"app_2026101722.log", "app_2026101723.log", "app_2026101800.log"}},
+		{FileConfig{DateSlice: FILE_SLICE_DATE_DAY}, time.Date(2026, 12, 30, 8, 0, 0, 0, testZone),
+			[]string{"app_20261230.log", "app_20261231.log", "app_20270101.log"}},
+		{FileConfig{DateSlice: FILE_SLICE_DATE_MONTH}, time.Date(2026, 11, 30, 8, 0, 0, 0, testZone),
+			[]string{"app_202611.log", "app_202612.log", "app_202701.log"}},
+		{FileConfig{DateSlice: FILE_SLICE_DATE_YEAR}, time.Date(2026, 1, 1, 0, 0, 0, 0, testZone),
+			[]string{"app_2026.log", "app_2027.log", "app_2028.log"}},
+		{FileConfig{RotateInterval: 15 * time.Minute}, time.Date(2026, 10, 17, 23, 37, 0, 0, testZone),
+			[]string{"app_202610172330.log", "app_202610172345.log", "app_202610180000.log"}},
+		// the boundary is midnight in UTC, not in the zone of the clock
+		{FileConfig{DateSlice: FILE_SLICE_DATE_DAY, RotateLocation: time.UTC}, time.Date(2026, 10, 17, 6, 0, 0, 0, testZone),
+			[]string{"app_20261016.log", "app_20261017.log", "app_20261018.log"}},
+	}
+	for _, c := range cases {
+		clock := &fakeTimerClock{now: c.start}
+		c.config.RollingType = RollingDaily
+		c.config.Clock = clock
+		adapter, logger, dir := newTestFileAdapter(t, c.config)
+
+		var want []string
+		for i := 0; i < 3; i++ {
+			logger.Info("line")
+			next := nextRotateTime(clock.Now(), &adapter.FileConfig)
+			// a write just before the boundary doesn't rotate
+			clock.Advance(next.Sub(clock.Now()) - time.Second)
+			logger.Info("line")
+			checkDirFiles(t, dir, append([]string{"app.log"}, want...)...)
+
+			clock.Advance(time.Second)
+			want = append(want, c.want[i])
+			checkDirFiles(t, dir, append([]string{"app.log"}, want...)...)
+		}
+		adapter.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestScheduledRotationEmptyAndStale(t *testing.T) {
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, _, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_HOUR,
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+
+	// a quiet service leaves no empty files
+	clock.Advance(3 * time.Hour)
+	checkDirFiles(t, dir, "app.log")
+	adapter.Close()
+
+	// a file written before a restart is rotated at once under the period of its last write
+	logfile := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(logfile, []byte("old\n"), 0644)
+	modTime := time.Date(2026, 10, 17, 11, 20, 0, 0, testZone)
+	os.Chtimes(logfile, modTime, modTime)
+
+	adapter, _, _ = newTestFileAdapterIn(t, dir, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_HOUR,
+		Clock:       clock,
+	})
+	defer adapter.Close()
+	checkDirFiles(t, dir, "app.log", "app_2026101711.log")
+}
+
+func TestSizeRotation(t *testing.T) {
+	if KB != 1024 || MB != 1024*KB || GB != 1024*MB || TB != 1024*GB {
+		t.Fatalf("wrong units, KB %d, MB %d, GB %d, TB %d", KB, MB, GB, TB)
+	}
+
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, logger, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingFileSize,
+		MaxSize:     100,
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+	defer adapter.Close()
+	// 25 bytes with the new line
+	adapter.SetEncoder(EncoderFunc(func(buf *Buffer, loggerMsg *Message) error {
+		buf.AppendString(loggerMsg.Body)
+		return nil
+	}))
+
+	line := strings.Repeat("x", 24)
+	for i := 0; i < 4; i++ {
+		logger.Info(line)
+	}
+	// exactly MaxSize, no rotation yet
+	if got := dirFiles(t, dir); len(got) != 1 || FileSize(filepath.Join(dir, "app.log")) != 100 {
+		t.Fatalf("wanted app.log of 100 bytes only, actual: %v", got)
+	}
+
+	logger.Info(line)
+	logger.Info(strings.Repeat("y", 200))
+	logger.Info(line)
+	// 4 lines, 1 line, the long line alone, and the current file
+	stem := "app.2026-10-17-10.00.00"
+	checkDirFiles(t, dir, "app.log", stem+".1.log", stem+".2.log", stem+".3.log")
+	for name, size := range map[string]int64{stem + ".1.log": 100, stem + ".2.log": 25, stem + ".3.log": 201, "app.log": 25} {
+		if got := FileSize(filepath.Join(dir, name)); got != size {
+			t.Errorf("%s wanted %d bytes, actual: %d", name, size, got)
+		}
+	}
+}
+
+func TestCheckRollingType(t *testing.T) {
+	cases := []FileConfig{
+		{RollingType: RollingDaily},
+		{RollingType: 3, DateSlice: FILE_SLICE_DATE_DAY},
+		{RollingType: RollingFileSize, DateSlice: FILE_SLICE_DATE_DAY},
+		{RollingType: RollingFileLine, MaxSize: 10},
+		{RollingType: RollingDaily, DateSlice: FILE_SLICE_DATE_DAY, MaxLine: -1},
+	}
+	for _, config := range cases {
+		config.FilePath, config.Filename = ".", "test.log"
+		if err := config.CheckConfig(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("RollingType %d wanted ErrInvalidConfig, actual: %v", config.RollingType, err)
+		}
+	}
+
+	// RollingType left unset is RollingDaily, like before size and line limits could be added to it
+	for _, config := range []FileConfig{
+		{FilePath: ".", Filename: "test.log", DateSlice: FILE_SLICE_DATE_DAY},
+		{FilePath: ".", Filename: "test.log", RollingType: RollingDaily, DateSlice: FILE_SLICE_DATE_DAY, MaxSize: 1},
+	} {
+		if err := config.CheckConfig(); err != nil {
+			t.Error(err)
+		}
+	}
+	if RollingDaily != 0 || RollingFileSize != 1 || RollingFileLine != 2 {
+		t.Errorf("rolling types changed: %d, %d, %d", RollingDaily, RollingFileSize, RollingFileLine)
+	}
+}
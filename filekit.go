@@ -48,11 +48,11 @@ func FileSize(file string) int64 {
 	return f.Size()
 }
 
-//get file lines
-//params : logfile
+//get file lines, compressed log files like app_20261017.log.gz are decompressed, see OpenLogFile
+//params : logfile, compressors of other formats
 //return : fileLine, error
-func FileLines(filename string) (int64, error) {
-	file, err := os.OpenFile(filename, os.O_RDONLY, 0766)
+func FileLines(filename string, compressors ...Compressor) (int64, error) {
+	file, err := OpenLogFile(filename, compressors...)
 	if err != nil {
 		return 0, err
 	}
@@ -1,11 +1,13 @@
 package glog
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,21 +16,30 @@ import (
 type LOGLEVEL int
 
 func (logLevel LOGLEVEL) LevelString() string {
-	return levelStringMap[logLevel]
+	levelLock.RLock()
+	name, ok := levelStringMap[logLevel]
+	levelLock.RUnlock()
+	if !ok {
+		return fmt.Sprintf("LEVEL(%d)", int(logLevel))
+	}
+	return name
 }
 
 func (logLevel LOGLEVEL) LevelInt() int {
 	return int(logLevel)
 }
 
+//DEBUG..OFF keep their numbers, TRACE takes the unused 0 and PANIC the number after OFF
+//levels are ordered by severity, not by number: TRACE < DEBUG < INFO < WARN < ERROR < PANIC < FATAL < OFF
 const (
-	_ LOGLEVEL = iota
+	TRACE LOGLEVEL = iota
 	DEBUG
 	INFO
 	WARN
 	ERROR
 	FATAL
 	OFF
+	PANIC
 )
 
 const (
@@ -39,26 +50,67 @@ const (
 	SlashMillisecondFormat = "2006/01/02 15:04:05.000"
 )
 
+//guard levelStringMap, LevelColorMap and updates of levelSeverities, see RegisterLevel
+var levelLock sync.RWMutex
+
 var levelStringMap = map[LOGLEVEL]string{
+	TRACE: "TRACE",
 	DEBUG: "DEBUG",
 	INFO:  "INFO",
 	WARN:  "WARN",
 	ERROR: "ERROR",
+	PANIC: "PANIC",
 	FATAL: "FATAL",
 	OFF:   "OFF",
 }
 
 type loggerMsg struct {
-	Itime  string   `json:"create_time"`
-	Ilevel LOGLEVEL `json:"level"`
-	Body   string   `json:"body"`
-	File   string   `json:"file"`
-	Line   int      `json:"line"`
+	Time    time.Time `json:"create_time"` // formatted by each adapter's encoder
+	Ilevel  LOGLEVEL  `json:"level"`
+	Name    string    `json:"logger,omitempty"`
+	Body    string    `json:"body"`
+	File    string    `json:"file"`
+	Line    int       `json:"line"`
+	Func    string    `json:"func,omitempty"`       // function name, ex "(*Server).Handle"
+	Package string    `json:"package,omitempty"`    // package path, ex "github.com/chgxtony/glog"
+	Stack   string    `json:"stacktrace,omitempty"` // goroutine stack trace, see SetStacktrace
+	Fields  []Field   `json:"-"`                    // extra key/value pairs
+
+	timeFormat string // logger time format, used by encoders without their own time layout
+}
+
+// Message is the exported name of loggerMsg, adapters and encoders outside this package use it
+type Message = loggerMsg
+
+var loggerMsgPool = sync.Pool{
+	New: func() interface{} {
+		return &loggerMsg{}
+	},
+}
+
+func getLoggerMsg() *loggerMsg {
+	return loggerMsgPool.Get().(*loggerMsg)
+}
+
+//reset and return loggerMsg to the pool, adapters must not keep it after Write returns
+func putLoggerMsg(msg *loggerMsg) {
+	*msg = loggerMsg{}
+	loggerMsgPool.Put(msg)
+}
+
+// json form of loggerMsg, Fields are flattened into extra keys
+func (msg *loggerMsg) MarshalJSON() ([]byte, error) {
+	buf := GetBuffer()
+	defer buf.Free()
+	defaultJSONEncoder.Encode(buf, msg)
+	return append([]byte(nil), buf.Bytes()...), nil
 }
 
 func formatLoggerMsg(loggerMsg *loggerMsg) string {
-	msg := fmt.Sprintf("%s [%5s] [%s:%d] %s", loggerMsg.Itime, loggerMsg.Ilevel.LevelString(), loggerMsg.File, loggerMsg.Line, loggerMsg.Body)
-	return msg
+	buf := GetBuffer()
+	defer buf.Free()
+	defaultTextEncoder.Encode(buf, loggerMsg)
+	return buf.String()
 }
 
 // 抽象的Logger接口
@@ -66,7 +118,7 @@ type AbstractLogger interface {
 	ID() string
 	Name() string
 	Init() error
-	Write(loggerMsg *loggerMsg) error
+	Write(loggerMsg *loggerMsg) error // loggerMsg is reused after Write returns, don't keep it
 	Flush()
 	LoggerConfig
 }
@@ -74,8 +126,10 @@ type AbstractLogger interface {
 // Logger配置信息
 type LoggerConfig interface {
 	IsJson() bool
-	Level() LOGLEVEL
-	SetLevel(loglevel LOGLEVEL)
+	Encoder() Encoder // if not set, json or text encoder selected by IsJson()
+	SetEncoder(encoder Encoder)
+	Level() LOGLEVEL            // called on every message, concurrently with SetLevel
+	SetLevel(loglevel LOGLEVEL) // must be safe while Level() is read, see adapterLevel
 	//TimeFormat string // "ex 2006-01-02 15:04:05.000"
 }
 
@@ -93,6 +147,32 @@ type adapterLoggerFunc func() AbstractLogger
 
 var adapters = make(map[string]adapterLoggerFunc)
 
+// errors returned by Attach, NewLogger and adapter constructors, check them with errors.Is
+var (
+	ErrAdapterNotRegistered = errors.New("logger: adapter is not registered")
+	ErrDuplicateAdapter     = errors.New("logger: adapter already attached")
+	ErrAdapterInit          = errors.New("logger: adapter init failed")
+	ErrInvalidConfig        = errors.New("logger: invalid config")
+)
+
+// error returned when Init() of an adapter failed, it matches ErrAdapterInit and unwraps to the Init() error
+type AdapterInitError struct {
+	ID  string
+	Err error
+}
+
+func (e *AdapterInitError) Error() string {
+	return fmt.Sprintf("%s: [%s], error: %v", ErrAdapterInit, e.ID, e.Err)
+}
+
+func (e *AdapterInitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AdapterInitError) Is(target error) bool {
+	return target == ErrAdapterInit
+}
+
 //Register logger adapter
 func Register(adapterName string, loggerAdapter adapterLoggerFunc) {
 
@@ -104,26 +184,67 @@ func Register(adapterName string, loggerAdapter adapterLoggerFunc) {
 
 //
 type Logger struct {
-	globalTimeFormat string           // global timeFormat
-	callerFlag       bool             // if set true, use runtime.Caller(), performance will be affected.
-	lock             sync.Mutex       //sync lock
-	adapterArr       []AbstractLogger // adapter arrays
-	msgChan          chan *loggerMsg  // message channel
-	isSync           bool             // is sync
-	wait             sync.WaitGroup   // process wait
-	signalChan       chan string
+	dropped          uint64         // messages dropped by async overflow policy, first field for 64-bit atomic alignment
+	globalTimeFormat string         // global timeFormat, used by adapters without their own TimeFormat
+	clock            Clock          // time source of messages
+	callerFlag       bool           // if set true, use runtime.Caller(), performance will be affected.
+	caller           CallerConfig   // what caller information is recorded
+	callerSkip       int            // extra caller frames to skip, set by AddCallerSkip()
+	lock             sync.Mutex     //sync lock
+	adapterArr       atomic.Value   // []AbstractLogger, copy on write, never modify a loaded slice
+	async            atomic.Value   // *asyncWriter, nil when synchronous
+	exitFunc         func(code int) // called by Fatal after flush, default os.Exit
+	root             *Logger        // root logger of a child created by With() or Named(), nil for a root logger
+	name             string         // logger name set by Named()
+	fields           []Field        // fields bound by With()
+	stackLevel       int32          // lowest level with a stack trace, OFF disables stack traces
+}
+
+//snapshot of attached adapters, safe to range while Attach/Detach run
+func (logger *Logger) adapterList() []AbstractLogger {
+	adapterArr, _ := logger.base().adapterArr.Load().([]AbstractLogger)
+	return adapterArr
 }
 
 // set all adapter LogLevel
 func (logger *Logger) SetGlobalLevel(loglevel LOGLEVEL) {
-	for _, adapter := range logger.adapterArr {
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	for _, adapter := range root.adapterList() {
 		adapter.SetLevel(loglevel)
 	}
 }
 
+//set LogLevel of the adapter with ID adapterID
+func (logger *Logger) SetAdapterLevel(adapterID string, loglevel LOGLEVEL) {
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	for _, adapter := range root.adapterList() {
+		if adapter.ID() == adapterID {
+			adapter.SetLevel(loglevel)
+		}
+	}
+}
+
+//whether a message of level would be written by at least one adapter
+//adapter levels are read on every call, so adapter.SetLevel() takes effect at once
+func (logger *Logger) Enabled(level LOGLEVEL) bool {
+	for _, adapter := range logger.adapterList() {
+		if levelEnabled(adapter.Level(), level) {
+			return true
+		}
+	}
+	return false
+}
+
 func (logger *Logger) ShowLevel() map[string]string {
-	res := make(map[string]string, len(logger.adapterArr))
-	for _, adapter := range logger.adapterArr {
+	adapterArr := logger.adapterList()
+	res := make(map[string]string, len(adapterArr))
+	for _, adapter := range adapterArr {
 		res[adapter.ID()] = adapter.Level().LevelString()
 	}
 	return res
@@ -132,32 +253,33 @@ func (logger *Logger) ShowLevel() map[string]string {
 //start attach a logger adapter after lock
 //return : error
 func (logger *Logger) Attach(adapter AbstractLogger) error {
-	logger.lock.Lock()
-	defer logger.lock.Unlock()
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
 
-	return logger.attach(adapter)
+	return root.attach(adapter)
 }
 
 //attach a logger adapter
 //return : error
 func (logger *Logger) attach(adapter AbstractLogger) error {
-	for _, v := range logger.adapterArr {
+	adapterArr := logger.adapterList()
+	for _, v := range adapterArr {
 		if v.ID() == adapter.ID() {
-			printError("logger: adapter [%s] already attached!", adapter.ID())
+			return fmt.Errorf("%w: [%s]", ErrDuplicateAdapter, adapter.ID())
 		}
 	}
-	logFun, ok := adapters[adapter.Name()]
-	if !ok {
-		printError("logger: adapter %s is not registered", adapter.Name())
+	if _, ok := adapters[adapter.Name()]; !ok {
+		return fmt.Errorf("%w: %s", ErrAdapterNotRegistered, adapter.Name())
 	}
 
-	adapterLog := logFun()
-
-	if err := adapterLog.Init(); err != nil {
-		printError("logger: adapter %s init failed, error: %s", adapter.ID(), err.Error())
+	if err := adapter.Init(); err != nil {
+		return &AdapterInitError{ID: adapter.ID(), Err: err}
 	}
 
-	logger.adapterArr = append(logger.adapterArr, adapter)
+	newArr := make([]AbstractLogger, 0, len(adapterArr)+1)
+	newArr = append(newArr, adapterArr...)
+	logger.adapterArr.Store(append(newArr, adapter))
 
 	return nil
 }
@@ -165,96 +287,165 @@ func (logger *Logger) attach(adapter AbstractLogger) error {
 //start detach a logger adapter after lock
 //return : error
 func (logger *Logger) Detach(adapterID string) error {
-	logger.lock.Lock()
-	defer logger.lock.Unlock()
+	root := logger.base()
+	root.lock.Lock()
+	defer root.lock.Unlock()
 
-	return logger.detach(adapterID)
+	return root.detach(adapterID)
 }
 
 //detach a logger adapter
 //return : error
 func (logger *Logger) detach(adapterID string) error {
-	for i, v := range logger.adapterArr {
+	adapterArr := logger.adapterList()
+	for i, v := range adapterArr {
 		if v.ID() == adapterID {
-			logger.adapterArr = append(logger.adapterArr[:i], logger.adapterArr[i+1:]...)
+			newArr := make([]AbstractLogger, 0, len(adapterArr)-1)
+			newArr = append(newArr, adapterArr[:i]...)
+			logger.adapterArr.Store(append(newArr, adapterArr[i+1:]...))
 			break
 		}
 	}
 	return nil
 }
 
-//set logger synchronous false
-//params : buf , if not set, default cap(msgChan) = 100, if set, cap(msgChan) = buf[0]
-func (logger *Logger) SetAsync(buf ...int) {
-	logger.lock.Lock()
-	defer logger.lock.Unlock()
-	logger.isSync = false
+//writers log message
+//params : callerSkip, extra frames to skip above the public logging method
+//return : error
+func (logger *Logger) logInternalWithCaller(level LOGLEVEL, timeFormat string, name string, msg string, fields []Field, withCaller bool, callerSkip int) error {
 
-	msgChanLen := 100
-	if len(buf) > 0 {
-		msgChanLen = buf[0]
+	loggerMsg := getLoggerMsg()
+	loggerMsg.File = "null"
+	if withCaller {
+		// skip runtime.Callers, logInternalWithCaller, logInternal and the public logging method
+		var pcs [1]uintptr
+		if runtime.Callers(4+callerSkip, pcs[:]) > 0 {
+			frame, _ := runtime.CallersFrames(pcs[:]).Next()
+			logger.setCaller(loggerMsg, frame)
+		}
 	}
+	if logger.stacktraceEnabled(level) {
+		loggerMsg.Stack = takeStacktrace(3 + callerSkip)
+	}
+	loggerMsg.Time = logger.clock.Now()
+	loggerMsg.timeFormat = timeFormat
+	loggerMsg.Ilevel = level
+	loggerMsg.Name = name
+	loggerMsg.Body = msg
+	loggerMsg.Fields = fields
+
+	logger.output(loggerMsg)
+	return nil
+}
 
-	logger.msgChan = make(chan *loggerMsg, msgChanLen)
-	logger.signalChan = make(chan string, 1)
-
-	if !logger.isSync {
-		go func() {
-			defer func() {
-				e := recover()
-				if e != nil {
-					fmt.Printf("%v", e)
-				}
-			}()
-			logger.startAsyncWrite()
-		}()
+//queue loggerMsg to the async writer or write it now, loggerMsg goes back to the pool after written
+//a message refused by a stopping writer waits until the writer is drained and SetAsyncConfig or Close is done with it,
+//then goes to the next writer or is written now, so adapters never get messages out of order from two goroutines
+func (logger *Logger) output(loggerMsg *loggerMsg) {
+	aw := logger.asyncWriter()
+	for aw != nil && !aw.push(loggerMsg) {
+		<-aw.doneChan
+		root := logger.base()
+		root.lock.Lock()
+		if root.asyncWriter() == aw {
+			// left by a Close which timed out, it is drained now
+			root.async.Store((*asyncWriter)(nil))
+		}
+		aw = root.asyncWriter()
+		root.lock.Unlock()
+	}
+	if aw == nil {
+		logger.writeToOutputs(loggerMsg)
+		putLoggerMsg(loggerMsg)
 	}
 }
 
-//writers log message
-//return : error
-func (logger *Logger) logInternalWithCaller(level LOGLEVEL, timeFormat string, msg string, withCaller bool) error {
+func (logger *Logger) SetGlobalTimeFormat(timeFormat string) {
+	logger.base().globalTimeFormat = timeFormat
+}
 
-	file := "null"
-	line := 0
+// time source of a Logger, replace it by SetClock for deterministic tests
+type Clock interface {
+	Now() time.Time
+}
 
-	if withCaller {
-		_, file, line, _ = runtime.Caller(2)
-	}
-	_, filename := path.Split(file)
+type systemClock struct{}
 
-	loggerMsg := &loggerMsg{
-		Itime:  time.Now().Format(timeFormat),
-		Ilevel: level,
-		Body:   msg,
-		File:   filename,
-		Line:   line,
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//set the time source of messages, call it before logging
+//params : clock, if nil, time.Now() is used
+func (logger *Logger) SetClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
 	}
+	logger.base().clock = clock
+}
 
-	if !logger.isSync {
-		logger.wait.Add(1)
-		logger.msgChan <- loggerMsg
-	} else {
-		logger.writeToOutputs(loggerMsg)
+func (logger *Logger) logInternal(level LOGLEVEL, msg string, kv ...interface{}) {
+	if !logger.Enabled(level) {
+		return
 	}
+	root := logger.base()
+	fields := logger.mergeFields(nil, kv)
+	root.logInternalWithCaller(level, root.globalTimeFormat, logger.name, msg, fields, root.callerFlag, logger.callerSkip)
+}
 
-	return nil
+//merge bound fields, fields extracted from context and kv fields of one call, later fields replace earlier ones with the same key
+//return : fields of loggerMsg
+func (logger *Logger) mergeFields(ctxFields []Field, kv []interface{}) []Field {
+	return appendFields(appendFields(logger.fields, ctxFields), fieldsFromKV(kv))
 }
 
-func (logger *Logger) SetGlobalTimeFormat(timeFormat string) {
-	logger.globalTimeFormat = timeFormat
+//the logger which owns adapters and the async writer
+func (logger *Logger) base() *Logger {
+	if logger.root != nil {
+		return logger.root
+	}
+	return logger
 }
 
-func (logger *Logger) logInternal(level LOGLEVEL, msg string) {
-	logger.logInternalWithCaller(level, logger.globalTimeFormat, msg, logger.callerFlag)
+//create a child logger, every message it writes carries the given fields
+//a field replaces a field of the parent with the same key
+//the child shares adapters, the async writer and time format with its root
+//params : kv, Field values or key/value pairs
+//return : child logger
+func (logger *Logger) With(kv ...interface{}) *Logger {
+	return &Logger{
+		root:       logger.base(),
+		name:       logger.name,
+		fields:     appendFields(logger.fields, fieldsFromKV(kv)),
+		callerSkip: logger.callerSkip,
+	}
+}
+
+//create a named child logger, names of nested children are joined by "."
+//params : name, ex "db"
+//return : child logger
+func (logger *Logger) Named(name string) *Logger {
+	if logger.name != "" && name != "" {
+		name = logger.name + "." + name
+	} else if name == "" {
+		name = logger.name
+	}
+	return &Logger{
+		root:       logger.base(),
+		name:       name,
+		fields:     logger.fields,
+		callerSkip: logger.callerSkip,
+	}
 }
 
 //sync writers message to loggerOutputs
 //params : loggerMsg
 func (logger *Logger) writeToOutputs(loggerMsg *loggerMsg) {
-	for _, adapter := range logger.adapterArr {
-		// writers level
-
+	for _, adapter := range logger.adapterList() {
+		// writers level, checked here too for adapters which compare levels by number only
+		if !levelEnabled(adapter.Level(), loggerMsg.Ilevel) {
+			continue
+		}
 		err := adapter.Write(loggerMsg)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "logger: unable writers loggerMsg to adapter:%v, error: %v\n", adapter.ID(), err)
@@ -262,56 +453,73 @@ func (logger *Logger) writeToOutputs(loggerMsg *loggerMsg) {
 	}
 }
 
-//start async writers by read logger.msgChan
-func (logger *Logger) startAsyncWrite() {
-	for {
-		select {
-		case loggerMsg := <-logger.msgChan:
-			logger.writeToOutputs(loggerMsg)
-			logger.wait.Done()
-		case signal := <-logger.signalChan:
-			if signal == "flush" {
-				logger.flush()
-			}
-		}
-	}
-}
-
-//flush msgChan data
+//flush all adapters
 func (logger *Logger) flush() {
-	if !logger.isSync {
-		for {
-			if len(logger.msgChan) > 0 {
-				loggerMsg := <-logger.msgChan
-				logger.writeToOutputs(loggerMsg)
-				logger.wait.Done()
-				continue
-			}
-			break
-		}
-		for _, adapter := range logger.adapterArr {
-			adapter.Flush()
-		}
+	for _, adapter := range logger.adapterList() {
+		adapter.Flush()
 	}
 }
 
-//if SetAsync() or logger.isSync() is false, must call Flush() to flush msgChan data
+//wait queued messages written by the async writer, then flush all adapters
 func (logger *Logger) Flush() {
-	if !logger.isSync {
-		logger.signalChan <- "flush"
-		logger.wait.Wait()
+	logger = logger.base()
+	if aw := logger.asyncWriter(); aw != nil && aw.flush() {
 		return
 	}
 	logger.flush()
 }
 
+//replace the exit hook called by Fatal, tests can use it to avoid exiting
+//params : exitFunc, if nil, os.Exit is used
+func (logger *Logger) SetExitFunc(exitFunc func(code int)) {
+	if exitFunc == nil {
+		exitFunc = os.Exit
+	}
+	logger.base().exitFunc = exitFunc
+}
+
+//flush all adapters then exit with code 1
+func (logger *Logger) exit() {
+	logger.Flush()
+	logger.base().exitFunc(1)
+}
+
+//flush all adapters then panic with msg
+func (logger *Logger) panic(msg string) {
+	logger.Flush()
+	panic(msg)
+}
+
 func (logger *Logger) Fatal(msg string) {
 	logger.logInternal(FATAL, msg)
+	logger.exit()
 }
 
 func (logger *Logger) Fatalf(format string, a ...interface{}) {
 	msg := fmt.Sprintf(format, a...)
 	logger.logInternal(FATAL, msg)
+	logger.exit()
+}
+
+func (logger *Logger) Fatalw(msg string, kv ...interface{}) {
+	logger.logInternal(FATAL, msg, kv...)
+	logger.exit()
+}
+
+func (logger *Logger) Panic(msg string) {
+	logger.logInternal(PANIC, msg)
+	logger.panic(msg)
+}
+
+func (logger *Logger) Panicf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	logger.logInternal(PANIC, msg)
+	logger.panic(msg)
+}
+
+func (logger *Logger) Panicw(msg string, kv ...interface{}) {
+	logger.logInternal(PANIC, msg, kv...)
+	logger.panic(msg)
 }
 
 func (logger *Logger) Error(msg string) {
@@ -319,40 +527,91 @@ func (logger *Logger) Error(msg string) {
 }
 
 func (logger *Logger) Errorf(format string, a ...interface{}) {
+	if !logger.Enabled(ERROR) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.logInternal(ERROR, msg)
 }
 
+func (logger *Logger) Errorw(msg string, kv ...interface{}) {
+	logger.logInternal(ERROR, msg, kv...)
+}
+
 func (logger *Logger) Warn(msg string) {
 	logger.logInternal(WARN, msg)
 }
 
 func (logger *Logger) Warnf(format string, a ...interface{}) {
+	if !logger.Enabled(WARN) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.logInternal(WARN, msg)
 }
 
+func (logger *Logger) Warnw(msg string, kv ...interface{}) {
+	logger.logInternal(WARN, msg, kv...)
+}
+
 func (logger *Logger) Info(msg string) {
 	logger.logInternal(INFO, msg)
 }
 
 func (logger *Logger) Infof(format string, a ...interface{}) {
+	if !logger.Enabled(INFO) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.logInternal(INFO, msg)
 }
 
+func (logger *Logger) Infow(msg string, kv ...interface{}) {
+	logger.logInternal(INFO, msg, kv...)
+}
+
 func (logger *Logger) Debug(msg string) {
 	logger.logInternal(DEBUG, msg)
 }
 
 func (logger *Logger) Debugf(format string, a ...interface{}) {
+	if !logger.Enabled(DEBUG) {
+		return
+	}
 	msg := fmt.Sprintf(format, a...)
 	logger.logInternal(DEBUG, msg)
 }
 
-func printError(format string, v ...interface{}) {
-	fmt.Printf(format, v...)
-	os.Exit(0)
+func (logger *Logger) Debugw(msg string, kv ...interface{}) {
+	logger.logInternal(DEBUG, msg, kv...)
+}
+
+func (logger *Logger) Trace(msg string) {
+	logger.logInternal(TRACE, msg)
+}
+
+func (logger *Logger) Tracef(format string, a ...interface{}) {
+	if !logger.Enabled(TRACE) {
+		return
+	}
+	msg := fmt.Sprintf(format, a...)
+	logger.logInternal(TRACE, msg)
+}
+
+func (logger *Logger) Tracew(msg string, kv ...interface{}) {
+	logger.logInternal(TRACE, msg, kv...)
+}
+
+//log at any level, including levels added by RegisterLevel
+//FATAL exits and PANIC panics like Fatalw and Panicw
+func (logger *Logger) Log(level LOGLEVEL, msg string, kv ...interface{}) {
+	logger.logInternal(level, msg, kv...)
+	switch level {
+	case FATAL:
+		logger.exit()
+	case PANIC:
+		logger.panic(msg)
+	}
 }
 
 //get default logger
@@ -360,25 +619,43 @@ func printError(format string, v ...interface{}) {
 func GetLogger() *Logger {
 	//default adapter console
 	consoleAdapter := NewConsoleAdapter(INFO, true, false)
-	return NewLogger(DashMillisecondFormat, true, consoleAdapter)
+	// console adapter is always registered and its Init never fails
+	logger, _ := NewLogger(DashMillisecondFormat, true, consoleAdapter)
+	return logger
 
 }
 
 // new logger
-func NewLogger(globalTimeFormat string, callerFlag bool, loggerAdapters ...AbstractLogger) *Logger {
+// return : logger, error of the first adapter failed to attach, the adapters attached before it are closed
+func NewLogger(globalTimeFormat string, callerFlag bool, loggerAdapters ...AbstractLogger) (*Logger, error) {
 	logger := &Logger{
 		globalTimeFormat: globalTimeFormat,
+		clock:            systemClock{},
 		callerFlag:       callerFlag,
-		adapterArr:       []AbstractLogger{},
-		msgChan:          make(chan *loggerMsg, 10),
-		isSync:           true,
-		wait:             sync.WaitGroup{},
-		signalChan:       make(chan string, 1),
+		exitFunc:         os.Exit,
 	}
+	logger.adapterArr.Store([]AbstractLogger{})
+	logger.stackLevel = int32(OFF)
 	for _, adapter := range loggerAdapters {
-		logger.attach(adapter)
+		if err := logger.attach(adapter); err != nil {
+			// adapters attached before may run a rotation timer or a cleaner
+			logger.closeAdapters()
+			return nil, err
+		}
 	}
-	return logger
+	return logger, nil
+}
+
+// detach all adapters and close the ones which implement io.Closer, ex FileAdapter
+func (logger *Logger) closeAdapters() {
+	for _, adapter := range logger.adapterList() {
+		if closer, ok := adapter.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: close adapter %v, error: %v\n", adapter.ID(), err)
+			}
+		}
+	}
+	logger.adapterArr.Store([]AbstractLogger{})
 }
 
 
@@ -1,13 +1,22 @@
 package glog
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestGetLogger(t *testing.T) {
 
 	logger := GetLogger()
+	logger.SetExitFunc(func(int) {})
 	levels := logger.ShowLevel()
 	fmt.Printf("%+v\n", levels)
 	logger.SetGlobalLevel(ERROR)
@@ -21,12 +30,364 @@ func TestGetLogger(t *testing.T) {
 
 func TestNewLogger(t *testing.T) {
 	console := NewConsoleAdapter(ERROR, false, true)
-	file := NewFileAdapter(INFO, ".", "test.log")
-	logger := NewLogger(DashMillisecondFormat, true,
+	file, err := NewFileAdapter(INFO, ".", "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewLogger(DashMillisecondFormat, true,
 		console, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.SetExitFunc(func(int) {})
 
 	fmt.Printf("%+v\n", logger.ShowLevel())
 	logger.Fatal("fatal msg")
 	logger.Error("error msg")
 	logger.Info("info msg")
 }
+
+func newBufferLogger(buf *bytes.Buffer, json bool) *Logger {
+	console := NewConsoleAdapter(DEBUG, false, json)
+	console.(*ConsoleAdapter).writer = buf
+	logger, _ := NewLogger(DashMillisecondFormat, true, console)
+	return logger
+}
+
+func TestLoggerFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+
+	logger.With("request_id", "r-1").Infow("hello", "user", "tony", Int("n", 2), "note", "a b")
+	want := `hello request_id=r-1 user=tony n=2 note="a b"`
+	if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, want) {
+		t.Errorf("wanted suffix : %s, actual: %s", want, got)
+	}
+
+	buf.Reset()
+	logger = newBufferLogger(buf, true)
+	logger.With(String("request_id", "r-1")).Errorw("failed", "err", errors.New("boom"))
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	if res["request_id"] != "r-1" || res["err"] != "boom" || res["body"] != "failed" {
+		t.Errorf("unexpected json output: %s", buf.String())
+	}
+}
+
+func TestLoggerFieldsReplace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	parent := logger.With("a", 1, "b", 1)
+
+	// a later field replaces the one with the same key, keys of the message not written are not prefixed
+	parent.With("b", 2).Infow("m", "a", 3, "a", 4, "package", "x", "logger", "y")
+	want := `"body":"m","file":"glog_test.go",`
+	got := buf.String()
+	if !strings.Contains(got, want) || !strings.HasSuffix(got, `"a":4,"b":2,"package":"x","logger":"y"}`+"\n") {
+		t.Errorf("unexpected json output: %s", got)
+	}
+
+	buf.Reset()
+	parent.Named("db").Infow("m", "logger", "y")
+	if got := buf.String(); !strings.Contains(got, `"logger":"db"`) || !strings.HasSuffix(got, `"a":1,"b":1,"fields.logger":"y"}`+"\n") {
+		t.Errorf("unexpected json output: %s", got)
+	}
+}
+
+func TestChildLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	child := logger.Named("db").With("tenant", "t1").Named("query")
+
+	child.SetGlobalLevel(WARN)
+	if logger.ShowLevel()["defaultConsole"] != "WARN" {
+		t.Errorf("child should share adapters with its root")
+	}
+	child.Info("ignored")
+	child.Warn("slow")
+
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	if res["logger"] != "db.query" || res["tenant"] != "t1" || res["body"] != "slow" {
+		t.Errorf("unexpected json output: %s", buf.String())
+	}
+}
+
+func TestFatalExit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+	code := -1
+	logger.SetExitFunc(func(c int) {
+		code = c
+	})
+
+	logger.Named("child").Fatalf("fatal %d", 1)
+	if code != 1 {
+		t.Errorf("wanted exit code : 1, actual: %d", code)
+	}
+	if !strings.Contains(buf.String(), "fatal 1") {
+		t.Errorf("fatal message not written before exit: %s", buf.String())
+	}
+}
+
+func TestPanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+
+	defer func() {
+		if e := recover(); e != "panic msg" {
+			t.Errorf("wanted panic : panic msg, actual: %v", e)
+		}
+		if !strings.Contains(buf.String(), "[PANIC]") {
+			t.Errorf("panic message not written: %s", buf.String())
+		}
+	}()
+	logger.Panic("panic msg")
+}
+
+type unregisteredAdapter struct {
+	ConsoleAdapter
+}
+
+func (*unregisteredAdapter) Name() string {
+	return "unregistered"
+}
+
+func TestAttachErrors(t *testing.T) {
+	console := NewConsoleAdapter(INFO, false, false)
+	logger, err := NewLogger(DashMillisecondFormat, true, console)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := logger.Attach(console); !errors.Is(err, ErrDuplicateAdapter) {
+		t.Errorf("wanted : %v, actual: %v", ErrDuplicateAdapter, err)
+	}
+	if err := logger.Attach(&unregisteredAdapter{}); !errors.Is(err, ErrAdapterNotRegistered) {
+		t.Errorf("wanted : %v, actual: %v", ErrAdapterNotRegistered, err)
+	}
+	if err := logger.Attach(&FileAdapter{AdapterLogger: AdapterLogger{Id: "emptyFile"}}); !errors.Is(err, ErrInvalidConfig) || !errors.Is(err, ErrAdapterInit) {
+		t.Errorf("wanted : %v, actual: %v", ErrInvalidConfig, err)
+	}
+	if _, err := NewFileAdapter(INFO, "", "test.log"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("wanted : %v, actual: %v", ErrInvalidConfig, err)
+	}
+
+	// the file adapter attached before the failed one is closed
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := NewFileAdapter(INFO, dir, "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLogger(DashMillisecondFormat, true, file, &unregisteredAdapter{}); !errors.Is(err, ErrAdapterNotRegistered) {
+		t.Errorf("wanted : %v, actual: %v", ErrAdapterNotRegistered, err)
+	}
+	if fw := file.(*FileAdapter).fileWriter; !fw.closed || fw.timer != nil {
+		t.Errorf("file adapter should be closed after NewLogger failed")
+	}
+}
+
+func TestAttachDetachConcurrent(t *testing.T) {
+	logger, _ := NewLogger(DashMillisecondFormat, false)
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info("msg")
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		console := NewConsoleAdapter(INFO, false, false)
+		console.(*ConsoleAdapter).writer = ioutil.Discard
+		console.(*ConsoleAdapter).Id = fmt.Sprintf("console%d", i%5)
+		logger.Detach(console.ID())
+		if err := logger.Attach(console); err != nil {
+			t.Fatal(err)
+		}
+		logger.ShowLevel()
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := len(logger.ShowLevel()); n != 5 {
+		t.Errorf("wanted adapters : 5, actual: %d", n)
+	}
+}
+
+type countingStringer struct {
+	n *int
+}
+
+func (c countingStringer) String() string {
+	*c.n++
+	return "counted"
+}
+
+func TestEnabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+	logger.SetGlobalLevel(WARN)
+
+	if logger.Enabled(INFO) || !logger.Enabled(WARN) || logger.With("k", "v").Enabled(DEBUG) {
+		t.Errorf("unexpected Enabled result, levels: %v", logger.ShowLevel())
+	}
+
+	n := 0
+	logger.Debugf("skip %v", countingStringer{&n})
+	logger.Warnf("write %v", countingStringer{&n})
+	if n != 1 {
+		t.Errorf("wanted format calls : 1, actual: %d", n)
+	}
+
+	logger.SetAdapterLevel("defaultConsole", DEBUG)
+	if !logger.Enabled(DEBUG) {
+		t.Errorf("DEBUG should be enabled after SetAdapterLevel")
+	}
+
+	// the level set on the adapter itself is seen by the logger
+	logger.adapterList()[0].SetLevel(ERROR)
+	if logger.Enabled(WARN) {
+		t.Errorf("WARN should be disabled after adapter.SetLevel")
+	}
+	logger.adapterList()[0].SetLevel(DEBUG)
+	logger.Debug("after adapter.SetLevel")
+	if !strings.Contains(buf.String(), "after adapter.SetLevel") {
+		t.Errorf("DEBUG not written after adapter.SetLevel: %s", buf.String())
+	}
+	logger.Detach("defaultConsole")
+	if logger.Enabled(FATAL) {
+		t.Errorf("no level should be enabled without adapters")
+	}
+}
+
+// run with -race, levels are changed while other goroutines log
+func TestSetLevelWhileLogging(t *testing.T) {
+	logger := newBufferLogger(&bytes.Buffer{}, false)
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := NewFileAdapter(INFO, dir, "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Attach(file); err != nil {
+		t.Fatal(err)
+	}
+	defer file.(*FileAdapter).Close()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				logger.Debugf("msg %d", j)
+				logger.Enabled(INFO)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	levels := []LOGLEVEL{DEBUG, WARN, OFF}
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			logger.Detach("defaultFile")
+			return
+		default:
+		}
+		logger.SetGlobalLevel(levels[i%len(levels)])
+		logger.SetAdapterLevel("defaultFile", levels[(i+1)%len(levels)])
+		logger.adapterList()[0].SetLevel(levels[(i+2)%len(levels)])
+	}
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestAdapterTimeFormat(t *testing.T) {
+	local := &bytes.Buffer{}
+	localConsole := NewConsoleAdapter(INFO, false, false)
+	localConsole.(*ConsoleAdapter).writer = local
+
+	utc := &bytes.Buffer{}
+	utcConsole := NewConsoleAdapter(INFO, false, true)
+	utcConsole.(*ConsoleAdapter).Id = "utcConsole"
+	utcConsole.(*ConsoleAdapter).writer = utc
+	utcConsole.(*ConsoleAdapter).SetTimeFormat(time.RFC3339, time.UTC)
+
+	logger, err := NewLogger(DashSecondFormat, false, localConsole, utcConsole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shanghai := time.FixedZone("CST", 8*3600)
+	logger.SetClock(fixedClock{time.Date(2019, 4, 1, 20, 0, 0, 0, shanghai)})
+	logger.Info("hello")
+
+	if !strings.HasPrefix(local.String(), "2019-04-01 20:00:00 [ INFO]") {
+		t.Errorf("unexpected local output: %s", local.String())
+	}
+	if !strings.HasPrefix(utc.String(), `{"create_time":"2019-04-01T12:00:00Z"`) {
+		t.Errorf("unexpected utc output: %s", utc.String())
+	}
+}
+
+// run with -race, the encoder is rebuilt while other goroutines log
+func TestSetTimeFormatWhileLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	console := logger.adapterList()[0].(*ConsoleAdapter)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			logger.Info("msg")
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			console.SetTimeFormat(DashSecondFormat, nil)
+			console.SetEncoder(nil)
+		}
+	}
+
+	console.SetTimeFormat(time.RFC3339, time.UTC)
+	logger.SetClock(fixedClock{time.Date(2019, 4, 1, 20, 0, 0, 0, time.FixedZone("CST", 8*3600))})
+	buf.Reset()
+	logger.Info("hello")
+	if !strings.HasPrefix(buf.String(), `{"create_time":"2019-04-01T12:00:00Z"`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
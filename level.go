@@ -0,0 +1,200 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// other names accepted by ParseLevel
+var levelAliases = map[string]LOGLEVEL{
+	"WARNING": WARN,
+	"ERR":     ERROR,
+}
+
+// distance between the severities of the built-in levels, RegisterLevel puts levels between them
+const severityStep = 1000
+
+// severity of OFF, higher than any level
+const offSeverity = math.MaxInt64
+
+// map[LOGLEVEL]int64, severities of the registered levels, copy on write under levelLock, never modify a loaded map
+var levelSeverities atomic.Value
+
+func init() {
+	levelSeverities.Store(map[LOGLEVEL]int64{
+		TRACE: 0,
+		DEBUG: 1 * severityStep,
+		INFO:  2 * severityStep,
+		WARN:  3 * severityStep,
+		ERROR: 4 * severityStep,
+		PANIC: 4*severityStep + severityStep/2,
+		FATAL: 5 * severityStep,
+		OFF:   offSeverity,
+	})
+}
+
+// severity of a level, levels are ordered by severity instead of by number
+// unregistered levels are ordered by number around the built-in levels, ex LOGLEVEL(9) is above FATAL
+func levelSeverity(level LOGLEVEL) int64 {
+	if severity, ok := levelSeverities.Load().(map[LOGLEVEL]int64)[level]; ok {
+		return severity
+	}
+	return int64(level) * severityStep
+}
+
+// whether a message of level passes threshold, the level of an adapter, a logger or a stack trace
+// OFF is the highest severity and nothing is logged at OFF, so a threshold of OFF disables every level
+func levelEnabled(threshold, level LOGLEVEL) bool {
+	severity := levelSeverity(level)
+	return severity < offSeverity && severity >= levelSeverity(threshold)
+}
+
+// add a level right above another one, ex RegisterLevel(20, "NOTICE", cyan, INFO) between INFO and WARN
+// a registered level is filtered, encoded and colored like the built-in levels, log it by Log or LogCtx
+// params : value, any number not used by another level, it only identifies the level, ex in configs
+// params : name, must not be used by another level
+// params : color, console color, nil for no color
+// params : above, a registered level below OFF, the new level sits between it and the next higher level
+// return : error wrapping ErrInvalidConfig
+func RegisterLevel(value LOGLEVEL, name string, color COLOR, above LOGLEVEL) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty name of level %d", ErrInvalidConfig, int(value))
+	}
+
+	levelLock.Lock()
+	defer levelLock.Unlock()
+
+	if old, ok := levelStringMap[value]; ok {
+		return fmt.Errorf("%w: level %d already registered as %s", ErrInvalidConfig, int(value), old)
+	}
+	for level, levelName := range levelStringMap {
+		if levelName == name {
+			return fmt.Errorf("%w: level name %s already used by %d", ErrInvalidConfig, name, int(level))
+		}
+	}
+
+	severities := levelSeverities.Load().(map[LOGLEVEL]int64)
+	low, ok := severities[above]
+	if !ok || above == OFF {
+		return fmt.Errorf("%w: level %s must sit above a registered level below OFF", ErrInvalidConfig, name)
+	}
+	high := int64(offSeverity)
+	for _, severity := range severities {
+		if severity > low && severity < high {
+			high = severity
+		}
+	}
+	if high == offSeverity {
+		// the highest level before OFF, keep the step of the built-in levels
+		high = low + 2*severityStep
+	}
+	severity := low + (high-low)/2
+	if severity == low {
+		return fmt.Errorf("%w: no room for level %s above %s", ErrInvalidConfig, name, above.LevelString())
+	}
+
+	newSeverities := make(map[LOGLEVEL]int64, len(severities)+1)
+	for level, severity := range severities {
+		newSeverities[level] = severity
+	}
+	newSeverities[value] = severity
+	levelSeverities.Store(newSeverities)
+	levelStringMap[value] = name
+	if color != nil {
+		LevelColorMap[value] = color
+	}
+	return nil
+}
+
+// level of an adapter set by SetLevel, read atomically while messages are written
+// configs return their LogLevel field until SetLevel is called, LogLevel itself is never written
+type adapterLevel struct {
+	level atomic.Value // LOGLEVEL
+}
+
+func (l *adapterLevel) get(initial LOGLEVEL) LOGLEVEL {
+	if level, ok := l.level.Load().(LOGLEVEL); ok {
+		return level
+	}
+	return initial
+}
+
+func (l *adapterLevel) set(level LOGLEVEL) {
+	l.level.Store(level)
+}
+
+// parse a level name case-insensitively, ex "info", "WARN", "warning", a registered name or a number
+// return : error wrapping ErrInvalidConfig if s is not a level
+func ParseLevel(s string) (LOGLEVEL, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if level, ok := levelAliases[name]; ok {
+		return level, nil
+	}
+
+	levelLock.RLock()
+	for level, levelName := range levelStringMap {
+		if levelName == name {
+			levelLock.RUnlock()
+			return level, nil
+		}
+	}
+	levelLock.RUnlock()
+
+	// numbers, and LEVEL(n) written by LevelString for unregistered levels
+	number := strings.TrimSuffix(strings.TrimPrefix(name, "LEVEL("), ")")
+	if n, err := strconv.Atoi(number); err == nil {
+		return LOGLEVEL(n), nil
+	}
+	return 0, fmt.Errorf("%w: unknown level %q", ErrInvalidConfig, s)
+}
+
+// String implements fmt.Stringer and flag.Value
+func (logLevel LOGLEVEL) String() string {
+	return logLevel.LevelString()
+}
+
+// Set implements flag.Value, ex flag.Var(&level, "level", "log level")
+func (logLevel *LOGLEVEL) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*logLevel = level
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler, levels are encoded by name
+func (logLevel LOGLEVEL) MarshalText() ([]byte, error) {
+	return []byte(logLevel.LevelString()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (logLevel *LOGLEVEL) UnmarshalText(text []byte) error {
+	return logLevel.Set(string(text))
+}
+
+// UnmarshalJSON accepts a level name or a number, configs written before MarshalText was added
+// keep their meaning, ex {"LogLevel":2} is INFO
+func (logLevel *LOGLEVEL) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return logLevel.Set(s)
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return fmt.Errorf("%w: unknown level %s", ErrInvalidConfig, data)
+	}
+	*logLevel = LOGLEVEL(n)
+	return nil
+}
@@ -0,0 +1,183 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// registered levels, AUDIT above FATAL and NOTICE between INFO and WARN
+const (
+	testAudit  LOGLEVEL = 10
+	testNotice LOGLEVEL = 20
+)
+
+var registerLevelsOnce sync.Once
+
+func registerLevels(t *testing.T) {
+	registerLevelsOnce.Do(func() {
+		if err := RegisterLevel(testAudit, "AUDIT", blue, FATAL); err != nil {
+			t.Fatal(err)
+		}
+		if err := RegisterLevel(testNotice, "NOTICE", green, INFO); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestRegisterLevel(t *testing.T) {
+	registerLevels(t)
+
+	buf := &bytes.Buffer{}
+	console := NewConsoleAdapter(ERROR, true, false)
+	console.(*ConsoleAdapter).writer = buf
+	logger, _ := NewLogger(DashMillisecondFormat, true, console)
+
+	logger.Info("filtered")
+	logger.Log(testAudit, "audited", "k", 1)
+	logger.Error("failed")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wanted 2 lines, actual: %q", buf.String())
+	}
+	if want := string(blue); !strings.HasPrefix(lines[0], want) || !strings.Contains(lines[0], "[AUDIT] ") {
+		t.Errorf("wanted a blue AUDIT line, actual: %q", lines[0])
+	}
+
+	// OFF disables the levels above it too
+	buf.Reset()
+	logger.SetGlobalLevel(OFF)
+	logger.Log(testAudit, "audited")
+	if buf.Len() != 0 || logger.Enabled(testAudit) || logger.Enabled(PANIC) {
+		t.Errorf("wanted no output at OFF, actual: %q", buf.String())
+	}
+
+	// NOTICE sits between INFO and WARN, PANIC between ERROR and FATAL
+	buf.Reset()
+	logger.SetGlobalLevel(testNotice)
+	logger.Info("filtered")
+	logger.Log(testNotice, "noticed")
+	logger.Warn("warned")
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], "[NOTICE] ") {
+		t.Errorf("wanted NOTICE and WARN lines, actual: %q", buf.String())
+	}
+	if logger.Enabled(INFO) || !logger.Enabled(testNotice) || levelEnabled(WARN, testNotice) {
+		t.Errorf("NOTICE must be between INFO and WARN")
+	}
+	if levelEnabled(FATAL, PANIC) || !levelEnabled(PANIC, FATAL) || !levelEnabled(ERROR, PANIC) {
+		t.Errorf("PANIC must be between ERROR and FATAL")
+	}
+
+	buf.Reset()
+	logger.SetGlobalLevel(TRACE)
+	logger.Trace("traced")
+	if !strings.Contains(buf.String(), "[TRACE] ") {
+		t.Errorf("wanted a TRACE line, actual: %q", buf.String())
+	}
+
+	msg := &Message{Ilevel: testAudit, Body: "m"}
+	b := GetBuffer()
+	defer b.Free()
+	NewJSONEncoderWithConfig(JSONEncoderConfig{LevelKey: "level", LevelEncoding: LevelLower}).Encode(b, msg)
+	if want := `{"level":"audit"}`; b.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, b.String())
+	}
+
+	if LOGLEVEL(26).LevelString() != "LEVEL(26)" {
+		t.Errorf("wanted LEVEL(26), actual: %s", LOGLEVEL(26).LevelString())
+	}
+}
+
+func TestRegisterLevelErrors(t *testing.T) {
+	registerLevels(t)
+
+	cases := []struct {
+		value LOGLEVEL
+		name  string
+		above LOGLEVEL
+	}{
+		{TRACE, "ZERO", INFO},
+		{OFF, "ABOVE", INFO},
+		{testAudit, "OTHER", INFO},
+		{26, "AUDIT", INFO},
+		{27, "", INFO},
+		{28, "LOUD", OFF},
+		{29, "ODD", 26},
+	}
+	for _, c := range cases {
+		if err := RegisterLevel(c.value, c.name, nil, c.above); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("RegisterLevel(%d, %q, %v) wanted ErrInvalidConfig, actual: %v", c.value, c.name, c.above, err)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	registerLevels(t)
+
+	cases := map[string]LOGLEVEL{
+		"trace":     TRACE,
+		"DEBUG":     DEBUG,
+		" Info ":    INFO,
+		"warn":      WARN,
+		"Warning":   WARN,
+		"err":       ERROR,
+		"error":     ERROR,
+		"panic":     PANIC,
+		"fatal":     FATAL,
+		"off":       OFF,
+		"audit":     testAudit,
+		"notice":    testNotice,
+		"3":         WARN,
+		"LEVEL(26)": 26,
+	}
+	for s, want := range cases {
+		level, err := ParseLevel(s)
+		if err != nil || level != want {
+			t.Errorf("ParseLevel(%q) wanted %v, actual: %v, %v", s, want, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("wanted ErrInvalidConfig, actual: %v", err)
+	}
+}
+
+func TestLevelMarshal(t *testing.T) {
+	type config struct {
+		Level LOGLEVEL
+	}
+	data, err := json.Marshal(config{Level: WARN})
+	if want := `{"Level":"WARN"}`; err != nil || string(data) != want {
+		t.Errorf("wanted %s, actual: %s, %v", want, data, err)
+	}
+
+	for _, input := range []string{`{"Level":"warning"}`, `{"Level":3}`} {
+		c := config{}
+		if err := json.Unmarshal([]byte(input), &c); err != nil || c.Level != WARN {
+			t.Errorf("%s: wanted WARN, actual: %v, %v", input, c.Level, err)
+		}
+	}
+	// numbers of configs written before MarshalText was added
+	for input, want := range map[string]LOGLEVEL{`{"LogLevel":1}`: DEBUG, `{"LogLevel":2}`: INFO, `{"LogLevel":5}`: FATAL, `{"LogLevel":6}`: OFF} {
+		c := ConsoleConfig{}
+		if err := json.Unmarshal([]byte(input), &c); err != nil || c.LogLevel != want {
+			t.Errorf("%s: wanted %v, actual: %v, %v", input, want, c.LogLevel, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"Level":"loud"}`), &config{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("wanted ErrInvalidConfig, actual: %v", err)
+	}
+
+	level := INFO
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&level, "level", "log level")
+	if err := flags.Parse([]string{"-level", "debug"}); err != nil || level != DEBUG {
+		t.Errorf("wanted DEBUG, actual: %v, %v", level, err)
+	}
+	if level.String() != "DEBUG" {
+		t.Errorf("wanted DEBUG, actual: %s", level.String())
+	}
+}
@@ -0,0 +1,118 @@
+package glog
+
+import (
+	"time"
+)
+
+// LogfmtEncoder output messages as logfmt key=value pairs,
+// ex: time="2019-04-01 12:00:00.000" level=info logger=db file=db.go line=42 msg="slow query" ms=120
+type LogfmtEncoder struct {
+	// time layout of the time key, if empty, the logger time format is used
+	TimeLayout string
+
+	// time zone of the time key, if nil, the time is not converted
+	Location *time.Location
+}
+
+func NewLogfmtEncoder() Encoder {
+	return &LogfmtEncoder{}
+}
+
+func (encoder *LogfmtEncoder) Encode(buf *Buffer, loggerMsg *Message) error {
+	buf.AppendString("time=")
+	layout := encoder.TimeLayout
+	if layout == "" {
+		layout = loggerMsg.timeFormat
+	}
+	if needsQuote(layout) {
+		// formatted times only contain spaces from the layout, no escaping needed
+		buf.AppendByte('"')
+		appendMsgTime(buf, loggerMsg, layout, encoder.Location)
+		buf.AppendByte('"')
+	} else {
+		appendMsgTime(buf, loggerMsg, layout, encoder.Location)
+	}
+
+	buf.AppendString(" level=")
+	for _, c := range []byte(loggerMsg.Ilevel.LevelString()) {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		buf.AppendByte(c)
+	}
+	if loggerMsg.Name != "" {
+		buf.AppendString(" logger=")
+		appendTextString(buf, loggerMsg.Name)
+	}
+	buf.AppendString(" file=")
+	appendTextString(buf, loggerMsg.File)
+	buf.AppendString(" line=")
+	buf.AppendInt(int64(loggerMsg.Line))
+	if loggerMsg.Func != "" {
+		buf.AppendString(" func=")
+		appendTextString(buf, loggerMsg.Func)
+	}
+	if loggerMsg.Package != "" {
+		buf.AppendString(" package=")
+		appendTextString(buf, loggerMsg.Package)
+	}
+	buf.AppendString(" msg=")
+	appendTextString(buf, loggerMsg.Body)
+
+	appendLogfmtFields(buf, loggerMsg, loggerMsg.Fields, "")
+	if loggerMsg.Stack != "" {
+		buf.AppendString(" stacktrace=")
+		appendTextString(buf, loggerMsg.Stack)
+	}
+	return nil
+}
+
+// whether key is written for loggerMsg itself, a field with it is prefixed by fieldKeyPrefix
+func logfmtMessageKey(key string, loggerMsg *Message) bool {
+	switch key {
+	case "time", "level", "file", "line", "msg":
+		return true
+	case "logger":
+		return loggerMsg.Name != ""
+	case "func":
+		return loggerMsg.Func != ""
+	case "package":
+		return loggerMsg.Package != ""
+	case "stacktrace":
+		return loggerMsg.Stack != ""
+	}
+	return false
+}
+
+// append fields as ' ' key=value, the fields of a Group get keys joined by '.', ex http.method=GET
+// params : prefix, keys of the enclosing groups, ex "http."
+func appendLogfmtFields(buf *Buffer, loggerMsg *Message, fields []Field, prefix string) {
+	for _, field := range fields {
+		if group, ok := field.Value.(fieldGroup); ok {
+			appendLogfmtFields(buf, loggerMsg, group, prefix+field.Key+".")
+			continue
+		}
+		buf.AppendByte(' ')
+		if prefix == "" && logfmtMessageKey(field.Key, loggerMsg) {
+			buf.AppendString(fieldKeyPrefix)
+		}
+		appendLogfmtKey(buf, prefix+field.Key)
+		buf.AppendByte('=')
+		appendTextValue(buf, field.Value)
+	}
+}
+
+// logfmt keys can't be quoted, replace spaces, '=' and '"' by '_'
+func appendLogfmtKey(buf *Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		buf.AppendByte(c)
+	}
+}
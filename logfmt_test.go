@@ -0,0 +1,55 @@
+package glog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	msg := &Message{
+		timeFormat: DashMillisecondFormat,
+		Time:       time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC),
+		Ilevel:     WARN,
+		Name:       "db",
+		Body:       `slow "query"`,
+		File:       "db.go",
+		Line:       42,
+		Fields:     []Field{Int("ms", 120), String("bad key", "a=b"), String("empty", "")},
+	}
+
+	buf := GetBuffer()
+	defer buf.Free()
+	NewLogfmtEncoder().Encode(buf, msg)
+	want := `time="2019-04-01 12:00:00.000" level=warn logger=db file=db.go line=42 msg="slow \"query\"" ms=120 bad_key="a=b" empty=""`
+	if buf.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, buf.String())
+	}
+
+	buf.Reset()
+	encoder := &LogfmtEncoder{TimeLayout: time.RFC3339}
+	msg.Fields = nil
+	encoder.Encode(buf, msg)
+	want = `time=2019-04-01T12:00:00Z level=warn logger=db file=db.go line=42 msg="slow \"query\""`
+	if buf.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, buf.String())
+	}
+
+	// fields named like the keys of the message are prefixed, group keys are joined by '.'
+	buf.Reset()
+	msg.Fields = []Field{String("level", "x"), Int("line", 7), Group("http", String("method", "GET"))}
+	encoder.Encode(buf, msg)
+	want = `time=2019-04-01T12:00:00Z level=warn logger=db file=db.go line=42 msg="slow \"query\"" fields.level=x fields.line=7 http.method=GET`
+	if buf.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, buf.String())
+	}
+
+	// logger and func are not written for this message, the fields keep their keys
+	buf.Reset()
+	msg.Name = ""
+	msg.Fields = []Field{String("logger", "x"), String("func", "y")}
+	encoder.Encode(buf, msg)
+	want = `time=2019-04-01T12:00:00Z level=warn file=db.go line=42 msg="slow \"query\"" logger=x func=y`
+	if buf.String() != want {
+		t.Errorf("wanted : %s, actual: %s", want, buf.String())
+	}
+}
@@ -0,0 +1,237 @@
+package glog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// named time layouts accepted by %time{...}, other values are used as go time layouts
+var patternTimeLayouts = map[string]string{
+	"ANSIC":            time.ANSIC,
+	"RFC822":           time.RFC822,
+	"RFC1123":          time.RFC1123,
+	"RFC3339":          time.RFC3339,
+	"RFC3339Nano":      time.RFC3339Nano,
+	"Kitchen":          time.Kitchen,
+	"Stamp":            time.Stamp,
+	"StampMilli":       time.StampMilli,
+	"StampMicro":       time.StampMicro,
+	"DashSecond":       DashSecondFormat,
+	"SlashSecond":      SlashSecondFormat,
+	"DashMillisecond":  DashMillisecondFormat,
+	"SlashMillisecond": SlashMillisecondFormat,
+	"ISO8601":          "2006-01-02T15:04:05.000Z0700",
+	"DateTime":         "2006-01-02 15:04:05",
+	"DateOnly":         "2006-01-02",
+	"TimeOnly":         "15:04:05",
+	"UnixDate":         time.UnixDate,
+}
+
+// one compiled directive or literal of a pattern
+type patternPart func(buf *Buffer, loggerMsg *Message)
+
+// PatternEncoder format messages by a layout pattern, see NewPatternEncoder
+type PatternEncoder struct {
+	pattern string
+	parts   []patternPart
+}
+
+// NewPatternEncoder compile a layout pattern, ex "%time{RFC3339} [%level] %logger %caller{short} %msg %fields"
+//
+//	%time           time formatted by the logger time format
+//	%time{layout}   time formatted by a named layout (RFC3339, ISO8601, ...) or a go time layout
+//	%time{layout|zone}  time converted to a zone (UTC, Local or an IANA name like Asia/Shanghai) first
+//	%level          level name, %level{lower} for lower case, %level{pad} right aligned to 5 chars
+//	%logger         logger name set by Named()
+//	%caller         file:line, %caller{short} always use the file name, %caller{full} the file as recorded
+//	%file, %line    file and line number
+//	%func           function name, %package package path, empty unless enabled by Logger.SetCallerConfig
+//	%msg            message body
+//	%fields         key=value pairs, the space before it is omitted when there are no fields
+//	%stacktrace     a new line and the stack trace, empty unless enabled by Logger.SetStacktrace
+//	%%              a literal %
+func NewPatternEncoder(pattern string) (*PatternEncoder, error) {
+	encoder := &PatternEncoder{pattern: pattern}
+	literal := strings.Builder{}
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			encoder.parts = append(encoder.parts, literalPart(literal.String()))
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c != '%' {
+			literal.WriteByte(c)
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '%' {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		// directive name
+		start := i + 1
+		end := start
+		for end < len(pattern) && pattern[end] >= 'a' && pattern[end] <= 'z' {
+			end++
+		}
+		name := pattern[start:end]
+		if name == "" {
+			return nil, fmt.Errorf("%w: pattern %q, missing directive at %d", ErrInvalidConfig, pattern, i)
+		}
+
+		// directive option in {}
+		option := ""
+		if end < len(pattern) && pattern[end] == '{' {
+			closeIndex := strings.IndexByte(pattern[end:], '}')
+			if closeIndex < 0 {
+				return nil, fmt.Errorf("%w: pattern %q, unclosed { at %d", ErrInvalidConfig, pattern, end)
+			}
+			option = pattern[end+1 : end+closeIndex]
+			end += closeIndex + 1
+		}
+
+		// the space before %fields is written only when there are fields
+		spaceBefore := false
+		if name == "fields" && strings.HasSuffix(literal.String(), " ") {
+			s := literal.String()
+			literal.Reset()
+			literal.WriteString(s[:len(s)-1])
+			spaceBefore = true
+		}
+		flushLiteral()
+
+		part, err := compileDirective(name, option, spaceBefore)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern %q, %v", ErrInvalidConfig, pattern, err)
+		}
+		encoder.parts = append(encoder.parts, part)
+		i = end - 1
+	}
+	flushLiteral()
+
+	return encoder, nil
+}
+
+func (encoder *PatternEncoder) Encode(buf *Buffer, loggerMsg *Message) error {
+	for _, part := range encoder.parts {
+		part(buf, loggerMsg)
+	}
+	return nil
+}
+
+// the pattern the encoder was compiled from
+func (encoder *PatternEncoder) Pattern() string {
+	return encoder.pattern
+}
+
+func literalPart(s string) patternPart {
+	return func(buf *Buffer, loggerMsg *Message) {
+		buf.AppendString(s)
+	}
+}
+
+func compileDirective(name, option string, spaceBefore bool) (patternPart, error) {
+	switch name {
+	case "time":
+		var location *time.Location
+		if i := strings.LastIndexByte(option, '|'); i >= 0 {
+			zone := option[i+1:]
+			option = option[:i]
+			loc, err := time.LoadLocation(zone)
+			if err != nil {
+				return nil, fmt.Errorf("unknown time zone %q of %%time", zone)
+			}
+			location = loc
+		}
+		layout, ok := patternTimeLayouts[option]
+		if !ok {
+			layout = option
+		}
+		return func(buf *Buffer, loggerMsg *Message) {
+			appendMsgTime(buf, loggerMsg, layout, location)
+		}, nil
+	case "level":
+		switch option {
+		case "", "upper":
+			return func(buf *Buffer, loggerMsg *Message) {
+				buf.AppendString(loggerMsg.Ilevel.LevelString())
+			}, nil
+		case "lower":
+			return func(buf *Buffer, loggerMsg *Message) {
+				for _, c := range []byte(loggerMsg.Ilevel.LevelString()) {
+					if c >= 'A' && c <= 'Z' {
+						c += 'a' - 'A'
+					}
+					buf.AppendByte(c)
+				}
+			}, nil
+		case "pad":
+			return func(buf *Buffer, loggerMsg *Message) {
+				level := loggerMsg.Ilevel.LevelString()
+				for i := len(level); i < 5; i++ {
+					buf.AppendByte(' ')
+				}
+				buf.AppendString(level)
+			}, nil
+		}
+	case "logger":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendString(loggerMsg.Name)
+		}, nil
+	case "caller":
+		switch option {
+		case "", "full":
+			return func(buf *Buffer, loggerMsg *Message) {
+				buf.AppendString(loggerMsg.File)
+				buf.AppendByte(':')
+				buf.AppendInt(int64(loggerMsg.Line))
+			}, nil
+		case "short":
+			return func(buf *Buffer, loggerMsg *Message) {
+				buf.AppendString(path.Base(loggerMsg.File))
+				buf.AppendByte(':')
+				buf.AppendInt(int64(loggerMsg.Line))
+			}, nil
+		}
+	case "file":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendString(loggerMsg.File)
+		}, nil
+	case "line":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendInt(int64(loggerMsg.Line))
+		}, nil
+	case "func":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendString(loggerMsg.Func)
+		}, nil
+	case "package":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendString(loggerMsg.Package)
+		}, nil
+	case "msg":
+		return func(buf *Buffer, loggerMsg *Message) {
+			buf.AppendString(loggerMsg.Body)
+		}, nil
+	case "fields":
+		return func(buf *Buffer, loggerMsg *Message) {
+			appendTextFields(buf, loggerMsg.Fields, "", spaceBefore)
+		}, nil
+	case "stacktrace":
+		return func(buf *Buffer, loggerMsg *Message) {
+			if loggerMsg.Stack != "" {
+				buf.AppendByte('\n')
+				buf.AppendString(loggerMsg.Stack)
+			}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown directive %%%s", name)
+	}
+	return nil, fmt.Errorf("unknown option %q of %%%s", option, name)
+}
@@ -0,0 +1,58 @@
+package glog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPatternEncoder(t *testing.T) {
+	msg := &Message{
+		timeFormat: DashMillisecondFormat,
+		Time:       time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC),
+		Ilevel:     WARN,
+		Name:       "db",
+		Body:       "slow query",
+		File:       "db.go",
+		Line:       42,
+	}
+
+	cases := []struct {
+		pattern string
+		fields  []Field
+		want    string
+	}{
+		{"%time{RFC3339} %level %logger %caller{short} %msg %fields", nil,
+			"2019-04-01T12:00:00Z WARN db db.go:42 slow query"},
+		{"%time{RFC3339} %level %logger %caller{short} %msg %fields", []Field{Int("ms", 120), String("sql", "select 1")},
+			`2019-04-01T12:00:00Z WARN db db.go:42 slow query ms=120 sql="select 1"`},
+		{"%time [%level{pad}] [%file:%line] %msg", nil,
+			"2019-04-01 12:00:00.000 [ WARN] [db.go:42] slow query"},
+		{"level=%level{lower} 100%% %msg", nil,
+			"level=warn 100% slow query"},
+		{"%time{2006/01/02} %msg", nil,
+			"2019/04/01 slow query"},
+	}
+
+	for _, c := range cases {
+		encoder, err := NewPatternEncoder(c.pattern)
+		if err != nil {
+			t.Fatalf("pattern %q: %v", c.pattern, err)
+		}
+		msg.Fields = c.fields
+		buf := GetBuffer()
+		encoder.Encode(buf, msg)
+		if buf.String() != c.want {
+			t.Errorf("pattern %q, wanted : %s, actual: %s", c.pattern, c.want, buf.String())
+		}
+		buf.Free()
+	}
+}
+
+func TestPatternEncoderInvalid(t *testing.T) {
+	for _, pattern := range []string{"%unknown", "%time{RFC3339", "%level{mixed}", "100% done"} {
+		if _, err := NewPatternEncoder(pattern); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("pattern %q, wanted : %v, actual: %v", pattern, ErrInvalidConfig, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package glog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// match rotated files of logfile by the names rotatedFilename produces for config, compressed or not,
+// the active file and files of other rolling types or date layouts don't match
+//
+//	RollingDaily : app_20261017.log, app_20261017.1.log, app_20261017.log.gz, the digits of rotateFormat
+//	RollingFileSize, RollingFileLine : app.2026-10-17-15.04.05.1.log
+//
+// submatch 1 is the date or time of the name, submatch 2 the index
+func rotatedFilePattern(logfile string, config *FileConfig) *regexp.Regexp {
+	filenameSuffix := path.Ext(logfile)
+	prefix := strings.TrimSuffix(path.Base(logfile), filenameSuffix)
+	compressed := regexp.QuoteMeta(gzipExtension)
+	if config.Compressor != nil {
+		compressed += "|" + regexp.QuoteMeta(config.Compressor.Extension())
+	}
+	stem := `\.(\d{4}-\d{2}-\d{2}-\d{2}\.\d{2}\.\d{2})`
+	if config.rollingByDate() {
+		stem = fmt.Sprintf(`_(\d{%d})`, len(config.rotateFormat()))
+	}
+	return regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + stem + `(?:\.(\d+))?` +
+		regexp.QuoteMeta(filenameSuffix) + "(?:" + compressed + ")?$")
+}
+
+// compress rotated files by Compressor, then delete them by MaxBackups, MaxAge and MaxTotalSize,
+// in a background goroutine so the write path never waits
+type fileCleaner struct {
+	logfile      string
+	pattern      *regexp.Regexp
+	clock        Clock // time source of MaxAge
+	compressor   Compressor
+	maxBackups   int
+	maxAge       time.Duration
+	maxTotalSize int64
+
+	triggerChan chan struct{}
+	stopChan    chan struct{}
+	doneChan    chan struct{} // closed when the cleaner goroutine exits
+}
+
+func newFileCleaner(logfile string, config *FileConfig) *fileCleaner {
+	var clock Clock = systemClock{}
+	if config.Clock != nil {
+		clock = config.Clock
+	}
+	return &fileCleaner{
+		logfile:      logfile,
+		pattern:      rotatedFilePattern(logfile, config),
+		clock:        clock,
+		compressor:   config.Compressor,
+		maxBackups:   config.MaxBackups,
+		maxAge:       config.MaxAge,
+		maxTotalSize: int64(config.MaxTotalSize),
+		triggerChan:  make(chan struct{}, 1),
+		stopChan:     make(chan struct{}),
+		doneChan:     make(chan struct{}),
+	}
+}
+
+// ask the cleaner goroutine to clean, never blocks
+func (cleaner *fileCleaner) trigger() {
+	select {
+	case cleaner.triggerChan <- struct{}{}:
+	default:
+	}
+}
+
+func (cleaner *fileCleaner) run() {
+	defer close(cleaner.doneChan)
+	for {
+		select {
+		case <-cleaner.triggerChan:
+			cleaner.cleanAndReport()
+		case <-cleaner.stopChan:
+			// a rotation right before stop is still cleaned
+			select {
+			case <-cleaner.triggerChan:
+				cleaner.cleanAndReport()
+			default:
+			}
+			return
+		}
+	}
+}
+
+// stop the cleaner goroutine after a pending clean
+func (cleaner *fileCleaner) stop() {
+	close(cleaner.stopChan)
+	<-cleaner.doneChan
+}
+
+func (cleaner *fileCleaner) cleanAndReport() {
+	if err := cleaner.compress(); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: compress rotated files of %s: %v\n", cleaner.logfile, err)
+	}
+	if err := cleaner.clean(); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: clean rotated files of %s: %v\n", cleaner.logfile, err)
+	}
+}
+
+// delete rotated files, oldest first, beyond maxBackups, older than maxAge,
+// or while rotated files and the active file together exceed maxTotalSize
+func (cleaner *fileCleaner) clean() error {
+	dir := filepath.Dir(cleaner.logfile)
+	infos, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var totalSize int64
+	var backups []rotatedFile
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if info.Name() == filepath.Base(cleaner.logfile) {
+			totalSize += info.Size()
+			continue
+		}
+		if match := cleaner.pattern.FindStringSubmatch(info.Name()); match != nil {
+			totalSize += info.Size()
+			index, _ := strconv.Atoi(match[2])
+			backups = append(backups, rotatedFile{FileInfo: info, date: match[1], index: index})
+		}
+	}
+	// newest first, files with the same modification time, ex rotated in the same second, by date and index
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].newer(backups[j])
+	})
+
+	now := cleaner.clock.Now()
+	var firstErr error
+	for i := len(backups) - 1; i >= 0; i-- {
+		info := backups[i]
+		expired := (cleaner.maxBackups > 0 && i >= cleaner.maxBackups) ||
+			(cleaner.maxAge > 0 && now.Sub(info.ModTime()) > cleaner.maxAge) ||
+			(cleaner.maxTotalSize > 0 && totalSize > cleaner.maxTotalSize)
+		if !expired {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		totalSize -= info.Size()
+	}
+	return firstErr
+}
+
+// a rotated file with the date or time and the index of its name
+type rotatedFile struct {
+	os.FileInfo
+	date  string // fixed width, compared as strings
+	index int    // 0 without index
+}
+
+func (file rotatedFile) newer(other rotatedFile) bool {
+	if !file.ModTime().Equal(other.ModTime()) {
+		return file.ModTime().After(other.ModTime())
+	}
+	if file.date != other.date {
+		return file.date > other.date
+	}
+	return file.index > other.index
+}
+
+// compress rotated files which are not compressed yet
+func (cleaner *fileCleaner) compress() error {
+	if cleaner.compressor == nil {
+		return nil
+	}
+	dir := filepath.Dir(cleaner.logfile)
+	infos, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !cleaner.pattern.MatchString(name) || strings.HasSuffix(name, cleaner.compressor.Extension()) ||
+			strings.HasSuffix(name, gzipExtension) {
+			continue
+		}
+		if err := compressFile(cleaner.compressor, filepath.Join(dir, name)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// list dir without sorting
+func readDir(dir string) ([]os.FileInfo, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdir(-1)
+}
@@ -0,0 +1,148 @@
+package glog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a Compressor copying files as is, for tests of file names
+type copyCompressor struct {
+	extension string
+}
+
+func (compressor copyCompressor) Extension() string {
+	return compressor.extension
+}
+
+func (copyCompressor) Compress(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func TestRotatedFilePattern(t *testing.T) {
+	others := []string{"app.log", "app_2.log", "app_access.log", "app_access_20261017.log", "other_20261017.log",
+		"app_20261017.txt", "app_20261017.log.bak", "app_20261017.log.gz.tmp"}
+	cases := []struct {
+		config  FileConfig
+		matched []string
+	}{
+		{FileConfig{DateSlice: FILE_SLICE_DATE_DAY, Compressor: copyCompressor{".zst"}},
+			[]string{"app_20261017.log", "app_20261017.12.log", "app_20261017.log.gz", "app_20261017.1.log.zst"}},
+		{FileConfig{DateSlice: FILE_SLICE_DATE_YEAR}, []string{"app_2026.log", "app_2026.1.log.gz"}},
+		{FileConfig{RotateInterval: 15 * time.Minute}, []string{"app_202610171415.log"}},
+		{FileConfig{RollingType: RollingFileSize, MaxSize: KB},
+			[]string{"app.2026-10-17-15.04.05.log", "app.2026-10-17-15.04.05.123.log", "app.2026-10-17-15.04.05.1.log.gz"}},
+	}
+	for i, c := range cases {
+		pattern := rotatedFilePattern("/var/log/app.log", &c.config)
+		// names of the other cases belong to other rolling types or date layouts
+		for j, other := range cases {
+			for _, name := range other.matched {
+				if pattern.MatchString(name) != (i == j) {
+					t.Errorf("case %d: %s wanted match %v", i, name, i == j)
+				}
+			}
+		}
+		for _, name := range others {
+			if pattern.MatchString(name) {
+				t.Errorf("case %d: %s should not match", i, name)
+			}
+		}
+	}
+}
+
+// create files of size bytes, the first modified at now, the others one more hour older each
+func createAgedFiles(t *testing.T, dir string, now time.Time, size int, names ...string) {
+	for i, name := range names {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFileCleaner(t *testing.T) {
+	// MaxAge is measured by the clock of the config, not by time.Now
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	backups := []string{"app_20261017.2.log", "app_20261017.1.log", "app_20261016.1.log", "app_20261015.1.log"}
+	cases := []struct {
+		config FileConfig
+		want   []string
+	}{
+		{FileConfig{MaxBackups: 2}, backups[:2]},
+		{FileConfig{MaxAge: 150 * time.Minute}, backups[:2]},
+		// app.log and 2 backups fit in 300 bytes
+		{FileConfig{MaxTotalSize: 300}, backups[:2]},
+		{FileConfig{MaxBackups: 3, MaxAge: 210 * time.Minute}, backups[:3]},
+		{FileConfig{MaxBackups: 10}, backups},
+	}
+	for i, c := range cases {
+		dir, err := ioutil.TempDir("", "glog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		createAgedFiles(t, dir, now, 100, append([]string{"app.log"}, backups...)...)
+		createAgedFiles(t, dir, now, 100, "app_access_20261001.log", "other.log", "app_2.log")
+
+		c.config.DateSlice = FILE_SLICE_DATE_DAY
+		c.config.Clock = &fakeTimerClock{now: now}
+		if err := newFileCleaner(filepath.Join(dir, "app.log"), &c.config).clean(); err != nil {
+			t.Fatal(err)
+		}
+		want := append([]string{"app.log", "app_access_20261001.log", "other.log", "app_2.log"}, c.want...)
+		if got := dirFiles(t, dir); strings.Join(got, ",") != strings.Join(sortedCopy(want), ",") {
+			t.Errorf("case %d wanted : %v, actual: %v", i, sortedCopy(want), got)
+		}
+	}
+}
+
+func TestFileCleanerSameModTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// rotated in the same second, the date and the index of the names tell the newest
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	names := []string{"app_20261016.3.log", "app_20261017.10.log", "app_20261017.2.log", "app_20261017.9.log.gz"}
+	for _, name := range names {
+		createAgedFiles(t, dir, now, 100, name)
+	}
+	config := &FileConfig{DateSlice: FILE_SLICE_DATE_DAY, MaxBackups: 2}
+	if err := newFileCleaner(filepath.Join(dir, "app.log"), config).clean(); err != nil {
+		t.Fatal(err)
+	}
+	checkDirFiles(t, dir, "app_20261017.10.log", "app_20261017.9.log.gz")
+}
+
+func TestFileAdapterRetention(t *testing.T) {
+	clock := &fakeTimerClock{now: time.Date(2026, 10, 17, 10, 0, 0, 0, testZone)}
+	adapter, logger, dir := newTestFileAdapter(t, FileConfig{
+		RollingType: RollingDaily,
+		DateSlice:   FILE_SLICE_DATE_DAY,
+		MaxLine:     1,
+		MaxBackups:  2,
+		Clock:       clock,
+	})
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 6; i++ {
+		logger.Info("line")
+	}
+	logger.Detach(adapter.ID())
+	if err := adapter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkDirFiles(t, dir, "app.log", "app_20261017.4.log", "app_20261017.5.log")
+}
@@ -0,0 +1,117 @@
+package glog
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// time layout of rotated file names when RotateInterval is set
+const rotateIntervalFormat = "200601021504"
+
+// Clock with timers, the time source of the rotation scheduler
+type TimerClock interface {
+	Clock
+	// call f in its own goroutine after d
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// a timer created by TimerClock.AfterFunc
+type Timer interface {
+	// prevent the timer from firing, return false if it already fired or stopped
+	Stop() bool
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// time zone of rotation boundaries and rotated file names
+func (config *FileConfig) rotateLocation() *time.Location {
+	if config.RotateLocation != nil {
+		return config.RotateLocation
+	}
+	if config.TimeLocation != nil {
+		return config.TimeLocation
+	}
+	return time.Local
+}
+
+// time layout of rotated file names
+func (config *FileConfig) rotateFormat() string {
+	if config.RotateInterval > 0 {
+		return rotateIntervalFormat
+	}
+	return dateSliceFormats[config.DateSlice]
+}
+
+// the first rotation boundary after t
+// RotateInterval boundaries are aligned to midnight, ex 00:00, 00:15, 00:30 for 15 minutes
+func nextRotateTime(t time.Time, config *FileConfig) time.Time {
+	t = t.In(config.rotateLocation())
+	year, month, day := t.Date()
+	loc := t.Location()
+
+	if config.RotateInterval > 0 {
+		midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		nextMidnight := time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		next := midnight.Add((t.Sub(midnight)/config.RotateInterval + 1) * config.RotateInterval)
+		// the last interval of a day ends at midnight
+		if next.After(nextMidnight) {
+			return nextMidnight
+		}
+		return next
+	}
+
+	switch config.DateSlice {
+	case FILE_SLICE_DATE_YEAR:
+		return time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	case FILE_SLICE_DATE_MONTH:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	case FILE_SLICE_DATE_DAY:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+	}
+}
+
+// start of the interval containing t, used to name files rotated by RotateInterval
+func rotateIntervalStart(t time.Time, config *FileConfig) time.Time {
+	t = t.In(config.rotateLocation())
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	return midnight.Add(t.Sub(midnight) / config.RotateInterval * config.RotateInterval)
+}
+
+// start the rotation scheduler, a file left by the previous period is rotated at once
+func (fw *FileWriter) startScheduler(config *FileConfig) {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	fw.scheduleRotation(config)
+}
+
+// rotate the file if its period is over, then schedule the next rotation, must hold lock
+// an empty file is not rotated, so quiet services don't leave empty files behind
+func (fw *FileWriter) scheduleRotation(config *FileConfig) {
+	if fw.closed {
+		return
+	}
+	now := fw.clock.Now()
+	next := nextRotateTime(time.Unix(fw.startTime, 0), config)
+	if !now.Before(next) {
+		if fw.size == 0 {
+			fw.startTime = now.Unix()
+		} else if err := fw.rotate(config); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: rotate %s: %v\n", fw.logfile, err)
+		}
+		next = nextRotateTime(now, config)
+	}
+
+	fw.timer = fw.clock.AfterFunc(next.Sub(now), func() {
+		fw.lock.Lock()
+		defer fw.lock.Unlock()
+
+		fw.scheduleRotation(config)
+	})
+}
@@ -0,0 +1,312 @@
+//go:build go1.21
+// +build go1.21
+
+package glog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+)
+
+const SLOG_ADAPTER_NAME = "slog"
+
+// map a slog level to LOGLEVEL, levels between two slog levels map to the lower one
+func levelFromSlog(level slog.Level) LOGLEVEL {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	default:
+		return ERROR
+	}
+}
+
+// map a LOGLEVEL to a slog level by severity, TRACE is LevelDebug-4, PANIC is LevelError+4,
+// FATAL and registered levels above it are LevelError+8
+func levelToSlog(level LOGLEVEL) slog.Level {
+	severity := levelSeverity(level)
+	switch {
+	case severity < levelSeverity(DEBUG):
+		return slog.LevelDebug - 4
+	case severity < levelSeverity(INFO):
+		return slog.LevelDebug
+	case severity < levelSeverity(WARN):
+		return slog.LevelInfo
+	case severity < levelSeverity(ERROR):
+		return slog.LevelWarn
+	case severity < levelSeverity(PANIC):
+		return slog.LevelError
+	case severity < levelSeverity(FATAL):
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError + 8
+	}
+}
+
+// SlogHandler is a slog.Handler writing to a *Logger, ex slog.New(glog.NewSlogHandler(logger))
+// attrs become fields, groups become Group fields: nested objects in json output, keys joined by '.' in text output
+type SlogHandler struct {
+	logger *Logger
+	fields []Field     // attrs added by WithAttrs before WithGroup
+	groups []slogGroup // groups opened by WithGroup, innermost last
+}
+
+// a group opened by WithGroup and the attrs added in it
+type slogGroup struct {
+	name   string
+	fields []Field
+}
+
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+func (handler *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.logger.Enabled(levelFromSlog(level))
+}
+
+func (handler *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	logger := handler.logger
+	level := levelFromSlog(record.Level)
+	if !logger.Enabled(level) {
+		return nil
+	}
+	root := logger.base()
+
+	attrs := make([]Field, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = appendSlogAttr(attrs, attr)
+		return true
+	})
+	attrs = handler.nest(attrs)
+
+	ctxFields := contextFields(ctx)
+	fields := appendFields(appendFields(appendFields(logger.fields, handler.fields), ctxFields), attrs)
+
+	// the logging call site, recorded by slog.Logger
+	var frame runtime.Frame
+	if record.PC != 0 {
+		frame, _ = runtime.CallersFrames([]uintptr{record.PC}).Next()
+	}
+
+	loggerMsg := getLoggerMsg()
+	loggerMsg.File = "null"
+	if root.callerFlag && record.PC != 0 {
+		root.setCaller(loggerMsg, frame)
+	}
+	if root.stacktraceEnabled(level) {
+		loggerMsg.Stack = takeStacktrace(0)
+		if record.PC != 0 {
+			loggerMsg.Stack = trimStacktrace(loggerMsg.Stack, frame.Function)
+		}
+	}
+	loggerMsg.Time = record.Time
+	if loggerMsg.Time.IsZero() {
+		loggerMsg.Time = root.clock.Now()
+	}
+	loggerMsg.timeFormat = root.globalTimeFormat
+	loggerMsg.Ilevel = level
+	loggerMsg.Name = logger.name
+	loggerMsg.Body = record.Message
+	loggerMsg.Fields = fields
+
+	root.output(loggerMsg)
+	return nil
+}
+
+// nest fields in the open groups with the attrs added in each group, groups left empty are omitted like slog does
+func (handler *SlogHandler) nest(fields []Field) []Field {
+	for i := len(handler.groups) - 1; i >= 0; i-- {
+		group := handler.groups[i]
+		if len(group.fields)+len(fields) == 0 {
+			continue
+		}
+		fields = []Field{Group(group.name, appendFields(group.fields, fields)...)}
+	}
+	return fields
+}
+
+func (handler *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return handler
+	}
+	// attrs go in the innermost open group, slices are copied, they are shared with handler
+	groups := handler.groups
+	fields := handler.fields
+	if len(groups) > 0 {
+		fields = groups[len(groups)-1].fields
+	}
+	fields = append(make([]Field, 0, len(fields)+len(attrs)), fields...)
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+
+	if len(groups) == 0 {
+		return &SlogHandler{logger: handler.logger, fields: fields}
+	}
+	groups = append(make([]slogGroup, 0, len(groups)), groups...)
+	groups[len(groups)-1].fields = fields
+	return &SlogHandler{logger: handler.logger, fields: handler.fields, groups: groups}
+}
+
+func (handler *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+	groups := append(handler.groups[:len(handler.groups):len(handler.groups)], slogGroup{name: name})
+	return &SlogHandler{logger: handler.logger, fields: handler.fields, groups: groups}
+}
+
+// convert an attr to fields, a group to a Group field, empty attrs and empty groups are ignored,
+// a group without key is inlined like slog does
+func appendSlogAttr(fields []Field, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		groupAttrs := attr.Value.Group()
+		if attr.Key == "" {
+			for _, groupAttr := range groupAttrs {
+				fields = appendSlogAttr(fields, groupAttr)
+			}
+			return fields
+		}
+		var group []Field
+		for _, groupAttr := range groupAttrs {
+			group = appendSlogAttr(group, groupAttr)
+		}
+		if len(group) == 0 {
+			return fields
+		}
+		return append(fields, Group(attr.Key, group...))
+	}
+	value := attr.Value.Any()
+	if err, ok := value.(error); ok {
+		value = errorValue{err}
+	}
+	return append(fields, Field{Key: attr.Key, Value: value})
+}
+
+type SlogConfig struct {
+	LogLevel LOGLEVEL
+	Handler  slog.Handler
+	LoggerConfig
+
+	level adapterLevel
+}
+
+func (config *SlogConfig) Level() LOGLEVEL {
+	return config.level.get(config.LogLevel)
+}
+
+// safe while messages are written, Level() returns loglevel from now on
+func (config *SlogConfig) SetLevel(loglevel LOGLEVEL) {
+	config.level.set(loglevel)
+}
+
+// messages are formatted by the slog.Handler
+func (config *SlogConfig) IsJson() bool {
+	return false
+}
+
+// messages are formatted by the slog.Handler, the encoder is not used
+func (config *SlogConfig) Encoder() Encoder {
+	return nil
+}
+
+func (config *SlogConfig) SetEncoder(encoder Encoder) {
+}
+
+// adapter forwarding messages to a slog.Handler
+// the logger name is added as attr "logger", file:line as "caller" and the stack trace as "stacktrace"
+type SlogAdapter struct {
+	SlogConfig
+	AdapterLogger
+}
+
+func (*SlogAdapter) Name() string {
+	return SLOG_ADAPTER_NAME
+}
+
+func (adapterSlog *SlogAdapter) Init() error {
+	if adapterSlog.Handler == nil {
+		return fmt.Errorf("%w: slog adapter without Handler", ErrInvalidConfig)
+	}
+	fmt.Printf("[GLOG] > [%s adapter] init success\n", adapterSlog.Name())
+	return nil
+}
+
+func (adapterSlog *SlogAdapter) Write(loggerMsg *loggerMsg) error {
+	if !levelEnabled(adapterSlog.Level(), loggerMsg.Ilevel) {
+		return nil
+	}
+	ctx := context.Background()
+	level := levelToSlog(loggerMsg.Ilevel)
+	if !adapterSlog.Handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	record := slog.NewRecord(loggerMsg.Time, level, loggerMsg.Body, 0)
+	if loggerMsg.Name != "" {
+		record.AddAttrs(slog.String("logger", loggerMsg.Name))
+	}
+	if loggerMsg.Line > 0 {
+		record.AddAttrs(slog.String("caller", fmt.Sprintf("%s:%d", loggerMsg.File, loggerMsg.Line)))
+	}
+	for _, field := range loggerMsg.Fields {
+		record.AddAttrs(slogAttr(field))
+	}
+	if loggerMsg.Stack != "" {
+		record.AddAttrs(slog.String("stacktrace", loggerMsg.Stack))
+	}
+
+	return adapterSlog.Handler.Handle(ctx, record)
+}
+
+// convert a field to an attr, a Group field to a slog group and an Err field to its error
+func slogAttr(field Field) slog.Attr {
+	switch v := field.Value.(type) {
+	case errorValue:
+		return slog.Any(field.Key, v.error)
+	case fieldGroup:
+		attrs := make([]slog.Attr, 0, len(v))
+		for _, groupField := range v {
+			attrs = append(attrs, slogAttr(groupField))
+		}
+		return slog.Attr{Key: field.Key, Value: slog.GroupValue(attrs...)}
+	}
+	return slog.Any(field.Key, field.Value)
+}
+
+// a slog.Handler has nothing to flush
+func (adapterSlog *SlogAdapter) Flush() {
+}
+
+// new slog adapter, ex NewSlogAdapter(INFO, slog.NewJSONHandler(os.Stderr, nil))
+func NewSlogAdapter(loglevel LOGLEVEL, handler slog.Handler) AbstractLogger {
+	return &SlogAdapter{
+		SlogConfig: SlogConfig{
+			LogLevel: loglevel,
+			Handler:  handler,
+		},
+		AdapterLogger: AdapterLogger{
+			Id: "defaultSlog",
+		},
+	}
+}
+
+func init() {
+	Register(SLOG_ADAPTER_NAME, func() AbstractLogger {
+		return &SlogAdapter{}
+	})
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
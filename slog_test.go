@@ -0,0 +1,143 @@
+//go:build go1.21
+// +build go1.21
+
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	logger.SetGlobalLevel(INFO)
+	slogger := slog.New(NewSlogHandler(logger.Named("api").With("svc", "users")))
+
+	slogger.Debug("filtered")
+	if buf.Len() != 0 {
+		t.Fatalf("wanted DEBUG filtered, actual: %s", buf.String())
+	}
+
+	slogger.With("a", 1).WithGroup("http").With("method", "GET").
+		Warn("slow", "ms", 120, slog.Group("req", "id", "r-1"), "err", errors.New("timeout"), slog.Group("empty"))
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	want := map[string]interface{}{
+		"level":  float64(WARN),
+		"logger": "api",
+		"body":   "slow",
+		"file":   "slog_test.go",
+		"svc":    "users",
+		"a":      float64(1),
+		"http": map[string]interface{}{
+			"method": "GET",
+			"ms":     float64(120),
+			"req":    map[string]interface{}{"id": "r-1"},
+			"err":    "timeout",
+		},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(res[k], v) {
+			t.Errorf("%s: wanted %v, actual: %v", k, v, res[k])
+		}
+	}
+
+	// groups without attrs are omitted, text output joins group keys by '.'
+	buf.Reset()
+	slogger.WithGroup("g").Info("plain")
+	if strings.Contains(buf.String(), `"g"`) {
+		t.Errorf("empty group should be omitted, actual: %s", buf.String())
+	}
+	buf.Reset()
+	text := newBufferLogger(buf, false)
+	slog.New(NewSlogHandler(text)).WithGroup("http").Info("text", "method", "GET", slog.Group("req", "id", "r-1"))
+	if want := "text http.method=GET http.req.id=r-1"; !strings.Contains(buf.String(), want) {
+		t.Errorf("wanted %q, actual: %s", want, buf.String())
+	}
+}
+
+func TestSlogHandlerStacktrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	logger.SetStacktrace(true)
+
+	slog.New(NewSlogHandler(logger)).ErrorContext(context.Background(), "failed")
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	stack, _ := res["stacktrace"].(string)
+	if !strings.HasPrefix(stack, "github.com/chgxtony/glog.TestSlogHandlerStacktrace\n\t") {
+		t.Errorf("wanted stacktrace starting at the test, actual: %q", stack)
+	}
+}
+
+func TestSlogAdapter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger, err := NewLogger(DashMillisecondFormat, true, NewSlogAdapter(INFO, handler))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("filtered")
+	logger.Named("db").Errorw("query failed", Err(errors.New("boom")), "rows", 3, Group("req", String("id", "r-1")))
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	want := map[string]interface{}{
+		"level":  "ERROR",
+		"msg":    "query failed",
+		"logger": "db",
+		"error":  "boom",
+		"rows":   float64(3),
+		"req":    map[string]interface{}{"id": "r-1"},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(res[k], v) {
+			t.Errorf("%s: wanted %v, actual: %v", k, v, res[k])
+		}
+	}
+	if caller, _ := res["caller"].(string); !strings.HasPrefix(caller, "slog_test.go:") {
+		t.Errorf("wanted caller slog_test.go, actual: %v", res["caller"])
+	}
+
+	if _, err := NewLogger(DashMillisecondFormat, true, NewSlogAdapter(INFO, nil)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("wanted ErrInvalidConfig without Handler, actual: %v", err)
+	}
+}
+
+func TestSlogLevels(t *testing.T) {
+	cases := []struct {
+		slogLevel slog.Level
+		level     LOGLEVEL
+	}{
+		{slog.LevelDebug - 4, TRACE},
+		{slog.LevelDebug, DEBUG},
+		{slog.LevelInfo, INFO},
+		{slog.LevelInfo + 2, INFO},
+		{slog.LevelWarn, WARN},
+		{slog.LevelError, ERROR},
+	}
+	for _, c := range cases {
+		if level := levelFromSlog(c.slogLevel); level != c.level {
+			t.Errorf("%v: wanted %v, actual: %v", c.slogLevel, c.level, level)
+		}
+		if c.slogLevel != slog.LevelInfo+2 && levelToSlog(c.level) != c.slogLevel {
+			t.Errorf("%v: wanted %v, actual: %v", c.level, c.slogLevel, levelToSlog(c.level))
+		}
+	}
+	if levelToSlog(PANIC) != slog.LevelError+4 || levelToSlog(FATAL) != slog.LevelError+8 {
+		t.Errorf("unexpected slog levels of PANIC and FATAL: %v, %v", levelToSlog(PANIC), levelToSlog(FATAL))
+	}
+}
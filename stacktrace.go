@@ -0,0 +1,69 @@
+package glog
+
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// default lowest level with a stack trace, see SetStacktrace
+const defaultStacktraceLevel = ERROR
+
+const maxStacktraceDepth = 64
+
+// attach a goroutine stack trace to messages at or above a level
+// params : enable, level, if not set, default ERROR
+func (logger *Logger) SetStacktrace(enable bool, level ...LOGLEVEL) {
+	stackLevel := OFF
+	if enable {
+		stackLevel = defaultStacktraceLevel
+		if len(level) > 0 {
+			stackLevel = level[0]
+		}
+	}
+	atomic.StoreInt32(&logger.base().stackLevel, int32(stackLevel))
+}
+
+// whether messages of level carry a stack trace
+func (logger *Logger) stacktraceEnabled(level LOGLEVEL) bool {
+	return levelEnabled(LOGLEVEL(atomic.LoadInt32(&logger.base().stackLevel)), level)
+}
+
+// format the stack of the current goroutine, one "function\n\tfile:line" per frame
+// params : skip, frames to skip above the caller of takeStacktrace
+func takeStacktrace(skip int) string {
+	pcs := make([]uintptr, maxStacktraceDepth)
+	// skip runtime.Callers and takeStacktrace
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	buf := GetBuffer()
+	defer buf.Free()
+	for {
+		frame, more := frames.Next()
+		if buf.Len() > 0 {
+			buf.AppendByte('\n')
+		}
+		buf.AppendString(frame.Function)
+		buf.AppendString("\n\t")
+		buf.AppendString(frame.File)
+		buf.AppendByte(':')
+		buf.AppendInt(int64(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// drop the frames above function, used when the logging call site is known by pc only
+func trimStacktrace(stack string, function string) string {
+	frame := function + "\n\t"
+	if strings.HasPrefix(stack, frame) {
+		return stack
+	}
+	if i := strings.Index(stack, "\n"+frame); i >= 0 {
+		return stack[i+1:]
+	}
+	return stack
+}
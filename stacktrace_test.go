@@ -0,0 +1,76 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStacktrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, false)
+
+	logger.Error("no stack")
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("stack trace should be disabled by default, actual: %s", buf.String())
+	}
+
+	logger.SetStacktrace(true)
+	buf.Reset()
+	logger.Warn("below level")
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("wanted no stack trace for WARN, actual: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Error("failed")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], "] failed") {
+		t.Fatalf("wanted a stack trace block, actual: %s", buf.String())
+	}
+	// the first frame is the caller of Error
+	if want := "glog.TestStacktrace"; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("wanted first frame %s, actual: %s", want, lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "\t") || !strings.Contains(lines[2], "stacktrace_test.go:") {
+		t.Errorf("wanted file:line of the first frame, actual: %s", lines[2])
+	}
+
+	logger.SetStacktrace(true, INFO)
+	buf.Reset()
+	logHelper(logger, "from helper")
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want := "glog.TestStacktrace"; len(lines) < 2 || !strings.HasSuffix(lines[1], want) {
+		t.Errorf("wanted first frame %s, actual: %s", want, buf.String())
+	}
+
+	logger.SetStacktrace(false)
+	buf.Reset()
+	logger.Error("disabled")
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("wanted no stack trace, actual: %s", buf.String())
+	}
+}
+
+func TestStacktraceJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newBufferLogger(buf, true)
+	logger.SetStacktrace(true)
+
+	logger.Errorw("failed", "k", 1)
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	stack, _ := res["stacktrace"].(string)
+	if !strings.HasPrefix(stack, "github.com/chgxtony/glog.TestStacktraceJSON\n\t") {
+		t.Errorf("wanted stacktrace starting at the test, actual: %q", stack)
+	}
+
+	buf.Reset()
+	logger.Info("no stack")
+	if strings.Contains(buf.String(), `"stacktrace":`) {
+		t.Errorf("wanted no stacktrace key, actual: %s", buf.String())
+	}
+}